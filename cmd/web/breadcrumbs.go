@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Breadcrumb is one segment of the current path, linking to the path up to
+// and including that segment.
+type Breadcrumb struct {
+	Label string
+	Href  string
+}
+
+// breadcrumbs splits path into labeled segments, so /dog-breeds/labrador gives
+// "dog-breeds" linking to /dog-breeds and "labrador" linking to
+// /dog-breeds/labrador. Segments found in app.config.breadcrumbLabels use
+// that label instead. The root path has no breadcrumbs.
+func (app *application) breadcrumbs(path string) []Breadcrumb {
+	var crumbs []Breadcrumb
+	var href strings.Builder
+
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" {
+			continue
+		}
+
+		href.WriteString("/")
+		href.WriteString(segment)
+
+		label, ok := app.config.breadcrumbLabels[segment]
+		if !ok {
+			label = segment
+			if unescaped, err := url.PathUnescape(segment); err == nil {
+				label = unescaped
+			}
+		}
+
+		crumbs = append(crumbs, Breadcrumb{Label: label, Href: href.String()})
+	}
+
+	return crumbs
+}
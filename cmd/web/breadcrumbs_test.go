@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestApplication_breadcrumbs(t *testing.T) {
+	app := application{
+		config: appConfig{breadcrumbLabels: map[string]string{"dog-breeds": "Dog Breeds"}},
+	}
+
+	req, _ := http.NewRequest("GET", "/dog-breeds/golden%20retriever/photos/", nil)
+	got := app.defaultData(req)["Breadcrumbs"]
+
+	want := []Breadcrumb{
+		{Label: "Dog Breeds", Href: "/dog-breeds"},
+		{Label: "golden retriever", Href: "/dog-breeds/golden%20retriever"},
+		{Label: "photos", Href: "/dog-breeds/golden%20retriever/photos"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong breadcrumbs; got %v, wanted %v", got, want)
+	}
+
+	if crumbs := app.breadcrumbs("/"); len(crumbs) != 0 {
+		t.Errorf("root path should have no breadcrumbs; got %v", crumbs)
+	}
+}
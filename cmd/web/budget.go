@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+)
+
+// templateSize estimates the size of a compiled template set as the length
+// of the source its parse trees print back to, layout and partials
+// included, so every page carries the cost of the shared parts too.
+func templateSize(tmpl *template.Template) int {
+	size := 0
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && t.Tree.Root != nil {
+			size += len(t.Tree.Root.String())
+		}
+	}
+	return size
+}
+
+// checkTemplateBudget reports the pages in built whose size is over
+// app.config.templateBudget. They are logged, and with
+// templateBudgetStrict also returned as an error naming each one.
+func (app *application) checkTemplateBudget(built map[string]*template.Template) error {
+	budget := app.config.templateBudget
+	if budget <= 0 {
+		return nil
+	}
+
+	var over []string
+	for t, tmpl := range built {
+		if size := templateSize(tmpl); size > budget {
+			over = append(over, fmt.Sprintf("%s (%d bytes)", t, size))
+		}
+	}
+	if len(over) == 0 {
+		return nil
+	}
+	sort.Strings(over)
+
+	if app.config.templateBudgetStrict {
+		return fmt.Errorf("templates over the %d byte budget: %s", budget, strings.Join(over, ", "))
+	}
+
+	app.log().Warn("templates over budget", "budget", budget, "templates", strings.Join(over, ", "))
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestApplication_buildTemplateCacheBudget(t *testing.T) {
+	app := newTestApp()
+	app.config.templateBudget = 4096
+
+	// warning only by default
+	var buf bytes.Buffer
+	app.logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+	if _, err := app.buildTemplateCache(); err != nil {
+		t.Fatalf("budget should only warn; got %v", err)
+	}
+	if !strings.Contains(buf.String(), "huge.page.gohtml (") || strings.Contains(buf.String(), "about.page.gohtml") {
+		t.Errorf("wrong templates reported; got %q", buf.String())
+	}
+	if _, ok := app.cachedTemplate("huge.page.gohtml"); !ok {
+		t.Error("over budget template not cached in warning mode")
+	}
+
+	// strict mode fails the build, keeping the previous cache, which is
+	// the one built above
+	previous, ok := app.cachedTemplate("about.page.gohtml")
+	if !ok {
+		t.Fatal("about.page.gohtml not cached by the first build")
+	}
+	app.config.templateBudgetStrict = true
+
+	_, err := app.buildTemplateCache()
+	if err == nil || !strings.Contains(err.Error(), "huge.page.gohtml") {
+		t.Fatalf("wanted an error naming huge.page.gohtml; got %v", err)
+	}
+	for _, name := range []string{"about.page.gohtml", "huge.page.gohtml"} {
+		if _, ok := app.cachedTemplate(name); !ok {
+			t.Errorf("%s dropped from the cache by a failed build", name)
+		}
+	}
+	if current, _ := app.cachedTemplate("about.page.gohtml"); current != previous {
+		t.Error("cache replaced despite a failed build")
+	}
+
+	// and disabled by default
+	app.config.templateBudget = 0
+	if _, err := app.buildTemplateCache(); err != nil {
+		t.Errorf("budget checked when disabled: %v", err)
+	}
+}
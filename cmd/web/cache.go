@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"html/template"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// TemplateCache stores compiled templates by key. The application depends
+// only on this interface, so the caching strategy can be swapped, and one
+// cache can be shared by several applications; the application prefixes
+// its keys (see cacheKey) so that they don't collide. Implementations must
+// be safe for concurrent use.
+type TemplateCache interface {
+	Get(key string) (*template.Template, bool)
+	Set(key string, tmpl *template.Template)
+	// Clear removes every entry whose key starts with prefix.
+	Clear(prefix string)
+}
+
+// mapCache is the default TemplateCache, a map guarded by a mutex.
+type mapCache struct {
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+}
+
+// newMapCache returns an empty mapCache.
+func newMapCache() *mapCache {
+	return &mapCache{templates: make(map[string]*template.Template)}
+}
+
+// Get returns the template stored under key.
+func (c *mapCache) Get(key string) (*template.Template, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tmpl, ok := c.templates[key]
+	return tmpl, ok
+}
+
+// Set stores tmpl under key.
+func (c *mapCache) Set(key string, tmpl *template.Template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.templates[key] = tmpl
+}
+
+// Clear removes every entry whose key starts with prefix.
+func (c *mapCache) Clear(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.templates {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.templates, key)
+		}
+	}
+}
+
+// ClearTenant removes every entry belonging to tenant; see tenantOf.
+func (c *mapCache) ClearTenant(tenant string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.templates {
+		if tenantOf(key) == tenant {
+			delete(c.templates, key)
+		}
+	}
+}
+
+// tenantSeparator ends the cache prefix in a cache key. It is not "/", as
+// template names may themselves be paths, such as "admin/users.page.gohtml".
+const tenantSeparator = "|"
+
+// cacheKey returns the key the template t is cached under: the template
+// name, prefixed with the application's cache prefix when one is set.
+func (app *application) cacheKey(t string) string {
+	if app.config.cachePrefix == "" {
+		return t
+	}
+	return app.config.cachePrefix + tenantSeparator + t
+}
+
+// cachedTemplate returns the template t from the cache, if there is one.
+func (app *application) cachedTemplate(t string) (*template.Template, bool) {
+	if app.cache == nil {
+		return nil, false
+	}
+	return app.cache.Get(app.cacheKey(t))
+}
+
+// cacheTemplate stores the template t in the cache, if there is one.
+func (app *application) cacheTemplate(t string, tmpl *template.Template) {
+	if app.cache != nil {
+		app.cache.Set(app.cacheKey(t), tmpl)
+	}
+}
+
+// clearCache removes the application's templates from the cache, leaving
+// those of any other application sharing it, along with their critical CSS,
+// cached fragments and text templates.
+func (app *application) clearCache() {
+	app.critical.clear()
+	app.fragments.clear()
+	app.clearTextTemplates()
+
+	switch c := app.cache.(type) {
+	case nil:
+	case tenantClearer:
+		c.ClearTenant(app.config.cachePrefix)
+	default:
+		// without tenantClearer, the default tenant's keys can't be told
+		// apart from others', so a cache the application doesn't share
+		// is cleared in full
+		prefix := ""
+		if app.config.cachePrefix != "" {
+			prefix = app.config.cachePrefix + tenantSeparator
+		}
+		c.Clear(prefix)
+	}
+}
+
+// cacheMissReason is why render had to build a template from disk.
+type cacheMissReason int
+
+const (
+	// missCachingDisabled: the cache is turned off
+	missCachingDisabled cacheMissReason = iota
+	// missAbsent: caching is on but the template isn't in the cache yet
+	missAbsent
+)
+
+func (r cacheMissReason) String() string {
+	switch r {
+	case missCachingDisabled:
+		return "caching disabled"
+	case missAbsent:
+		return "not in cache"
+	default:
+		return "unknown"
+	}
+}
+
+// logCacheMiss logs, at debug level, that t is being built from disk and why.
+func (app *application) logCacheMiss(t string, reason cacheMissReason) {
+	logger := app.log()
+
+	// skip building the attributes when debug logging is off
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+
+	logger.LogAttrs(context.Background(), slog.LevelDebug, "building template from disk",
+		slog.String("template", t),
+		slog.String("reason", reason.String()),
+	)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestApplication_sharedTemplateCache(t *testing.T) {
+	shared := newMapCache()
+
+	newApp := func(prefix string, cache TemplateCache) *application {
+		app := newTestApp()
+		app.cache = cache
+		app.config.useCache = true
+		app.config.cachePrefix = prefix
+		return app
+	}
+
+	appA := newApp("breeders", shared)
+	appB := newApp("breeders", shared)
+	other := newApp("other", shared)
+	private := newApp("breeders", newMapCache())
+
+	req, _ := http.NewRequest("GET", "/about", nil)
+	if _, err := appA.render(httptest.NewRecorder(), req, "about.page.gohtml", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// B shares A's cache and prefix, so it gets A's build without parsing
+	_, _ = appB.render(httptest.NewRecorder(), req, "about.page.gohtml", nil)
+	if appB.stats.cacheHits.Load() != 1 || appB.stats.cacheMisses.Load() != 0 {
+		t.Errorf("app B did not reuse app A's template: hits=%d misses=%d",
+			appB.stats.cacheHits.Load(), appB.stats.cacheMisses.Load())
+	}
+
+	// a different prefix on the same cache doesn't collide with A's key
+	if _, ok := other.cachedTemplate("about.page.gohtml"); ok {
+		t.Error("template visible under another application's prefix")
+	}
+
+	// and an application with its own cache sees nothing of it
+	if _, ok := private.cachedTemplate("about.page.gohtml"); ok {
+		t.Error("template visible in an unshared cache")
+	}
+
+	// clearing one application's templates leaves the others'
+	// (building a template from disk also caches it)
+	mustTemplate(t, other, "about.page.gohtml")
+	appA.clearCache()
+
+	if _, ok := appB.cachedTemplate("about.page.gohtml"); ok {
+		t.Error("clearCache left the application's template")
+	}
+	if _, ok := other.cachedTemplate("about.page.gohtml"); !ok {
+		t.Error("clearCache removed another application's template")
+	}
+}
+
+func TestMapCache_concurrentUse(t *testing.T) {
+	app := newTestApp()
+	app.config.useCache = true
+
+	done := make(chan bool)
+	for i := 0; i < 8; i++ {
+		go func() {
+			req, _ := http.NewRequest("GET", "/about", nil)
+			_, _ = app.render(httptest.NewRecorder(), req, "about.page.gohtml", nil)
+			app.clearCache()
+			done <- true
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+}
+
+func TestApplication_logCacheMiss(t *testing.T) {
+	var buf bytes.Buffer
+
+	app := newTestApp()
+	app.logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	lastReason := func() string {
+		var entry struct {
+			Template string `json:"template"`
+			Reason   string `json:"reason"`
+		}
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		if err := json.Unmarshal(lines[len(lines)-1], &entry); err != nil {
+			t.Fatal(err)
+		}
+		return entry.Reason
+	}
+
+	tests := []struct {
+		name     string
+		useCache bool
+		template string
+		want     string
+	}{
+		{"caching off", false, "about.page.gohtml", "caching disabled"},
+		{"not cached yet", true, "account.page.gohtml", "not in cache"},
+	}
+
+	for _, tt := range tests {
+		app.config.useCache = tt.useCache
+		if _, err := app.getTemplate(tt.template); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := lastReason(); got != tt.want {
+			t.Errorf("%s: wrong reason; got %q, wanted %q", tt.name, got, tt.want)
+		}
+	}
+
+	// a cache hit logs nothing
+	buf.Reset()
+	_, _ = app.getTemplate("account.page.gohtml")
+	if buf.Len() != 0 {
+		t.Errorf("cache hit was logged: %s", buf.String())
+	}
+
+	// nor does anything at the default log level
+	app.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	app.config.useCache = false
+	_, _ = app.getTemplate("about.page.gohtml")
+	if buf.Len() != 0 {
+		t.Errorf("cache miss logged above debug level: %s", buf.String())
+	}
+}
+
+func TestApplication_cacheCallbacks(t *testing.T) {
+	app := newTestApp()
+	app.config.useCache = true
+
+	var hits, misses []string
+	app.OnCacheHit = func(name string) { hits = append(hits, name) }
+	app.OnCacheMiss = func(name string) {
+		misses = append(misses, name)
+		// must not deadlock on the cache
+		app.cachedTemplate(name)
+	}
+
+	render := func(t string) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		_, _ = app.render(httptest.NewRecorder(), req, t, nil)
+	}
+
+	render("about.page.gohtml")
+	render("about.page.gohtml")
+	render("fragment.page.gohtml")
+
+	if !reflect.DeepEqual(misses, []string{"about.page.gohtml", "fragment.page.gohtml"}) {
+		t.Errorf("wrong misses; got %v", misses)
+	}
+	if !reflect.DeepEqual(hits, []string{"about.page.gohtml"}) {
+		t.Errorf("wrong hits; got %v", hits)
+	}
+
+	// without callbacks, nothing breaks
+	app.OnCacheHit, app.OnCacheMiss = nil, nil
+	render("about.page.gohtml")
+}
@@ -0,0 +1,35 @@
+package main
+
+import "strings"
+
+// cacheControlNoStore is sent for any template no rule matches, so pages are
+// only cached by browsers when they have been explicitly marked as safe.
+const cacheControlNoStore = "no-store"
+
+// cacheControlRule sets the Cache-Control header for every template whose
+// name starts with prefix.
+type cacheControlRule struct {
+	prefix string
+	value  string
+}
+
+// defaultCacheControl marks the static marketing pages as cacheable by the
+// browser. They are private all the same: every page carries per-visitor
+// data from defaultData and the middleware (theme, experiment variant,
+// device, flash messages, whether the visitor is signed in), so a shared
+// cache would hand one visitor's page to everyone.
+var defaultCacheControl = []cacheControlRule{
+	{prefix: "home.", value: "private, max-age=3600"},
+	{prefix: "about.", value: "private, max-age=3600"},
+}
+
+// cacheControlFor returns the Cache-Control value for template t. The first
+// matching rule wins.
+func (app *application) cacheControlFor(t string) string {
+	for _, rule := range app.config.cacheControl {
+		if strings.HasPrefix(t, rule.prefix) {
+			return rule.value
+		}
+	}
+	return cacheControlNoStore
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApplication_renderCacheControl(t *testing.T) {
+	app := newTestApp()
+	app.config.cacheControl = defaultCacheControl
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"about.page.gohtml", "private, max-age=3600"},
+		{"account.page.gohtml", "no-store"},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+
+		app.render(rr, req, tt.template, nil)
+
+		if got := rr.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s: wrong Cache-Control; got %q, wanted %q", tt.template, got, tt.want)
+		}
+	}
+}
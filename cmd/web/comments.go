@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"golang.org/x/net/html"
+)
+
+// StripComments is a Transform which removes HTML comments, so notes don't
+// reach visitors. html/template already drops comments written in template
+// text; this catches those arriving in template.HTML values, such as
+// content edited in a CMS or markup from a translation file. Conditional comments (<!--[if IE]>)
+// are kept, as they change what old browsers render, and so is everything
+// inside script, style and other raw text elements, where <!-- isn't a
+// comment at all. An unterminated comment is left as it is. It is enabled
+// in production by the -strip-comments flag.
+//
+// The page is run through the html tokenizer, which tells comments from raw
+// text as a browser would; everything but the dropped comments is copied
+// byte for byte.
+func StripComments(doc []byte, r *http.Request) ([]byte, error) {
+	var out bytes.Buffer
+	out.Grow(len(doc))
+
+	z := html.NewTokenizer(bytes.NewReader(doc))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return out.Bytes(), nil
+		}
+
+		raw := z.Raw()
+		if tt == html.CommentToken && bytes.HasSuffix(raw, []byte("-->")) && !conditionalComment(raw) {
+			continue
+		}
+		out.Write(raw)
+	}
+}
+
+// conditionalComment reports whether raw, a comment's source, is one of
+// the <!--[if ...]> or <!--<![endif]--> comments older browsers act on.
+func conditionalComment(raw []byte) bool {
+	body := bytes.TrimPrefix(raw, []byte("<!--"))
+	return bytes.HasPrefix(body, []byte("[if")) || bytes.HasPrefix(body, []byte("<![endif]"))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"plain", `<p>a<!-- TODO: fix -->b</p>`, `<p>ab</p>`},
+		{"multi line", "<p>a</p>\n<!--\n  notes\n-->\n<p>b</p>", "<p>a</p>\n\n<p>b</p>"},
+		{"conditional", `<!--[if IE]><link href="/ie.css"><![endif]-->`, `<!--[if IE]><link href="/ie.css"><![endif]-->`},
+		{"downlevel revealed", `<!--[if !IE]><!--><p>modern</p><!--<![endif]-->`, `<!--[if !IE]><!--><p>modern</p><!--<![endif]-->`},
+		{"script", `<script>var s = "<!-- kept -->";</script><!-- gone -->`, `<script>var s = "<!-- kept -->";</script>`},
+		{"style", `<STYLE>/* <!-- kept --> */</STYLE>`, `<STYLE>/* <!-- kept --> */</STYLE>`},
+		{"unterminated", `<p>a</p><!-- oops`, `<p>a</p><!-- oops`},
+		{"stray lt", `<p>1 < 2</p><!-- x -->`, `<p>1 < 2</p>`},
+		{"textarea", `<textarea><!-- kept --></textarea><!-- gone -->`, `<textarea><!-- kept --></textarea>`},
+		{"attribute", `<p title="<!-- kept -->">a</p>`, `<p title="<!-- kept -->">a</p>`},
+		{"source kept as is", `<P CLASS=x>a<br/><!-- gone --></P>`, `<P CLASS=x>a<br/></P>`},
+	}
+
+	for _, tt := range tests {
+		got, err := StripComments([]byte(tt.html), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %q, wanted %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplication_renderStripComments(t *testing.T) {
+	app := newTestApp()
+	app.documentTransforms = []Transform{StripComments}
+
+	// html/template drops comments in the template itself, so they come in
+	// trusted data, as CMS content would
+	if err := app.SetTemplate("comments.page.gohtml", `<main>{{.Data.Body}}</main>`); err != nil {
+		t.Fatal(err)
+	}
+	body := template.HTML(`<!-- TODO: remove --><!--[if IE]><p>old</p><![endif]--><p>page</p>`)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	if _, err := app.render(rr, req, "comments.page.gohtml", &templateData{Data: map[string]any{"Body": body}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(rr.Body.String(), "TODO") {
+		t.Errorf("comment not stripped; got %s", rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), "<!--[if IE]><p>old</p><![endif]-->") {
+		t.Errorf("conditional comment stripped; got %s", rr.Body.String())
+	}
+}
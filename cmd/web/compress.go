@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acceptsGzip reports whether the client accepts gzip, going by
+// Accept-Encoding. An encoding with q=0 is refused, and "*" stands for gzip
+// when gzip isn't listed by name.
+func acceptsGzip(r *http.Request) bool {
+	gzipQ, anyQ := -1.0, -1.0
+
+	for _, header := range r.Header.Values("Accept-Encoding") {
+		for _, encoding := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+			name = strings.TrimSpace(name)
+
+			switch {
+			case strings.EqualFold(name, "gzip"):
+				gzipQ = qValue(params)
+			case name == "*":
+				anyQ = qValue(params)
+			}
+		}
+	}
+
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
+
+// qValue returns the q parameter in params, the part of an Accept-Encoding
+// element after the first ";". It is 1 when missing, and 0 when malformed.
+func qValue(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if !strings.EqualFold(strings.TrimSpace(key), "q") {
+			continue
+		}
+
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
+
+// gzipBytes returns b compressed with gzip.
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
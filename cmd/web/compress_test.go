@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, GZIP", true},
+		{"gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0, deflate", false},
+		{"br, *", true},
+		{"*;q=0", false},
+		{"gzip;q=0, *", false},
+		{"gzip, *;q=0", true},
+		{"gzip;q=nonsense", false},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set("Accept-Encoding", tt.header)
+		}
+
+		if got := acceptsGzip(req); got != tt.want {
+			t.Errorf("%q: got %t, wanted %t", tt.header, got, tt.want)
+		}
+	}
+}
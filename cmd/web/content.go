@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"path/filepath"
+	texttemplate "text/template"
+)
+
+// textContentTypes are the content types rendered with text/template rather
+// than html/template, whose HTML-context escaping would corrupt them. These
+// templates are standalone documents without the base layout, and must escape
+// values themselves with the xml function.
+var textContentTypes = map[string]bool{
+	"image/svg+xml":   true,
+	"application/xml": true,
+	"text/xml":        true,
+	"text/plain":      true,
+}
+
+// renderContent renders the template t as contentType, which is also sent as
+// the Content-Type header. HTML goes through render as usual; the types in
+// textContentTypes are executed with text/template, but are otherwise
+// treated like pages: drafts and feature gates apply, errors and deadlines
+// are reported as render reports them, and the output is sent with the same
+// caching headers and compression. Like render, it returns the number of
+// bytes written to w.
+func (app *application) renderContent(w http.ResponseWriter, r *http.Request, t, contentType string, td *templateData) (int, error) {
+	if !textContentTypes[contentType] {
+		w.Header().Set("Content-Type", contentType)
+		return app.render(w, r, t, td)
+	}
+
+	app.stats.renders.Add(1)
+
+	buf, err := app.executeText(r, t, td)
+	if err != nil {
+		app.renderError(w, r, t, err)
+		return 0, err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	return app.send(w, r, t, buf.Bytes())
+}
+
+// executeText finds the text/template t, builds its data and executes it
+// into a buffer, so a failure doesn't send half a document.
+func (app *application) executeText(r *http.Request, t string, td *templateData) (*bytes.Buffer, error) {
+	if !app.canRender(r, t) {
+		return nil, errDraftNotFound
+	}
+	if app.features != nil && !app.features.Allow(t) {
+		return nil, errFeatureDisabled
+	}
+
+	tmpl, err := app.getTextTemplate(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if td == nil {
+		td = &templateData{}
+	}
+	td = app.composeData(r, td)
+
+	return executeNamed(r.Context(), tmpl, t, td)
+}
+
+// getTextTemplate returns the text/template t, from the cache when caching
+// is enabled, and otherwise parsed from the template directory. Like pages,
+// the cache hooks are called.
+func (app *application) getTextTemplate(t string) (*texttemplate.Template, error) {
+	reason := missCachingDisabled
+	if app.config.useCache {
+		app.cacheMu.RLock()
+		tmpl, ok := app.textTemplateMap[t]
+		app.cacheMu.RUnlock()
+		if ok {
+			app.stats.cacheHits.Add(1)
+			if app.OnCacheHit != nil {
+				app.OnCacheHit(t)
+			}
+			return tmpl, nil
+		}
+		reason = missAbsent
+	}
+
+	app.stats.cacheMisses.Add(1)
+	app.logCacheMiss(t, reason)
+	if app.OnCacheMiss != nil {
+		app.OnCacheMiss(t)
+	}
+
+	src, err := app.templateLoader().Load(filepath.Join(app.templateDir(), t))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := texttemplate.New(t).
+		Delims(app.config.leftDelim, app.config.rightDelim).
+		Funcs(texttemplate.FuncMap{"xml": xmlEscape}).
+		Parse(string(bytes.TrimPrefix(src, utf8BOM)))
+	if err != nil {
+		return nil, &ParseError{Template: t, Err: err}
+	}
+
+	if app.config.useCache {
+		app.cacheMu.Lock()
+		if app.textTemplateMap == nil {
+			app.textTemplateMap = make(map[string]*texttemplate.Template)
+		}
+		app.textTemplateMap[t] = tmpl
+		app.cacheMu.Unlock()
+	}
+
+	return tmpl, nil
+}
+
+// clearTextTemplates empties the cache of text/template templates.
+func (app *application) clearTextTemplates() {
+	app.cacheMu.Lock()
+	app.textTemplateMap = nil
+	app.cacheMu.Unlock()
+}
+
+// xmlEscape is the xml template function, escaping a value for use in XML
+// text or attribute values.
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestApplication_renderContentSVG(t *testing.T) {
+	app := newTestApp()
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/badge.svg", nil)
+
+	app.renderContent(rr, req, "badge.svg.gohtml", "image/svg+xml", &templateData{
+		Data: map[string]any{"label": "cats & <dogs>"},
+	})
+
+	if ct := rr.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Errorf("wrong content type; got %s", ct)
+	}
+
+	body := rr.Body.String()
+	if !strings.HasPrefix(body, `<?xml version="1.0" encoding="UTF-8"?>`) {
+		t.Errorf("XML declaration was escaped; got %s", body)
+	}
+	if !strings.Contains(body, `<svg xmlns="http://www.w3.org/2000/svg"`) {
+		t.Errorf("SVG markup was escaped; got %s", body)
+	}
+	if !strings.Contains(body, "<text x=\"5\" y=\"15\">cats &amp; &lt;dogs&gt;</text>") {
+		t.Errorf("data was not XML escaped; got %s", body)
+	}
+}
+
+func TestApplication_renderContentCache(t *testing.T) {
+	app := newTestApp()
+
+	misses := 0
+	app.OnCacheMiss = func(string) { misses++ }
+
+	render := func(header http.Header) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/badge.svg", nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		app.renderContent(rr, req, "badge.svg.gohtml", "image/svg+xml", &templateData{Data: map[string]any{"label": "cats"}})
+		return rr
+	}
+
+	// with caching off, nothing is stored
+	render(nil)
+	if app.textTemplateMap != nil {
+		t.Error("text template was cached with caching disabled")
+	}
+
+	app.config.useCache = true
+	render(nil)
+	render(nil)
+	if misses != 2 {
+		t.Errorf("wrong number of cache misses; got %d, wanted 2", misses)
+	}
+
+	// clearing the cache, as a reload does, drops it
+	app.clearCache()
+	rr := render(nil)
+	if misses != 3 {
+		t.Errorf("text template survived clearCache; got %d misses, wanted 3", misses)
+	}
+
+	// the response is tagged like a page
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if rr := render(http.Header{"If-None-Match": {etag}}); rr.Code != http.StatusNotModified {
+		t.Errorf("wrong status for a matching ETag; got %d", rr.Code)
+	}
+
+	// and feature gates apply
+	app.features = newFlagGate([]string{"badge.svg.gohtml"})
+	if rr := render(nil); rr.Code != http.StatusNotFound {
+		t.Errorf("wrong status for a disabled template; got %d", rr.Code)
+	}
+}
+
+// textLoader serves the templates in it by file name, and everything else
+// from disk.
+type textLoader map[string]string
+
+func (l textLoader) Load(path string) ([]byte, error) {
+	if src, ok := l[filepath.Base(path)]; ok {
+		return []byte(src), nil
+	}
+	return diskLoader{}.Load(path)
+}
+
+func TestApplication_renderContentErrors(t *testing.T) {
+	app := newTestApp()
+	app.loader = textLoader{
+		"broken.svg.gohtml": `<svg>{{xml .Data.label}</svg>`,
+		"slow.svg.gohtml":   `<svg>{{range .Data.items}}{{$.Data.sleeper.Sleep}}{{end}}</svg>`,
+	}
+
+	req, _ := http.NewRequest("GET", "/badge.svg", nil)
+
+	rr := httptest.NewRecorder()
+	_, err := app.renderContent(rr, req, "broken.svg.gohtml", "image/svg+xml", nil)
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Template != "broken.svg.gohtml" {
+		t.Errorf("syntax error: wanted a ParseError for broken.svg.gohtml, got %#v", err)
+	}
+	if body := rr.Body.String(); rr.Code != http.StatusInternalServerError || strings.Contains(body, "broken.svg.gohtml") {
+		t.Errorf("syntax error: wanted a generic 500; got %d %q", rr.Code, body)
+	}
+
+	// the badge's label must be a string
+	rr = httptest.NewRecorder()
+	_, err = app.renderContent(rr, req, "badge.svg.gohtml", "image/svg+xml", &templateData{Data: map[string]any{"label": 7}})
+	var ee *ExecError
+	if !errors.As(err, &ee) || ee.Template != "badge.svg.gohtml" {
+		t.Errorf("bad data: wanted an ExecError for badge.svg.gohtml, got %#v", err)
+	}
+	if rr.Code != http.StatusInternalServerError || strings.Contains(rr.Body.String(), "<svg") {
+		t.Errorf("bad data: wanted a clean 500; got %d %q", rr.Code, rr.Body.String())
+	}
+
+	calls := &atomic.Int64{}
+	td := &templateData{Data: map[string]any{
+		"items":   make([]int, 50),
+		"sleeper": countingSleeper{d: 2 * time.Millisecond, calls: calls},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	rr = httptest.NewRecorder()
+	_, err = app.renderContent(rr, req.WithContext(ctx), "slow.svg.gohtml", "image/svg+xml", td)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrong error; got %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("wrong response code; got %d, wanted 504", rr.Code)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// criticalCSSCache holds the critical CSS read for each template, keyed
+// like the template cache. An empty value records that a template has none.
+type criticalCSSCache struct {
+	mu  sync.RWMutex
+	css map[string]template.CSS
+}
+
+// criticalCSSPath returns where the critical CSS of the template t lives:
+// next to it, named after the page, so home.page.gohtml has home.critical.css.
+func (app *application) criticalCSSPath(t string) string {
+	name := strings.TrimSuffix(strings.TrimSuffix(t, ".page.gohtml"), ".gohtml")
+	return filepath.Join(app.templateDir(), name+".critical.css")
+}
+
+// criticalCSS returns the CSS to inline in the head of the template t, or
+// "" if it has none. The file is our own, so it is trusted as safe CSS.
+// With caching on it is read once per template, and cleared along with the
+// template cache.
+func (app *application) criticalCSS(t string) template.CSS {
+	key := app.cacheKey(t)
+
+	if app.config.useCache {
+		app.critical.mu.RLock()
+		css, ok := app.critical.css[key]
+		app.critical.mu.RUnlock()
+		if ok {
+			return css
+		}
+	}
+
+	var css template.CSS
+	src, err := app.templateLoader().Load(app.criticalCSSPath(t))
+	switch {
+	case err == nil:
+		css = template.CSS(src)
+	case !errors.Is(err, fs.ErrNotExist):
+		app.log().Error("reading critical CSS", "template", t, "err", err)
+		return ""
+	}
+
+	if app.config.useCache {
+		app.critical.mu.Lock()
+		if app.critical.css == nil {
+			app.critical.css = make(map[string]template.CSS)
+		}
+		app.critical.css[key] = css
+		app.critical.mu.Unlock()
+	}
+
+	return css
+}
+
+// clear forgets all critical CSS read so far.
+func (c *criticalCSSCache) clear() {
+	c.mu.Lock()
+	c.css = nil
+	c.mu.Unlock()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_renderCriticalCSS(t *testing.T) {
+	app := newTestApp()
+	app.config.useCache = true
+
+	render := func(page string) string {
+		req, _ := http.NewRequest("GET", "/", nil)
+		rr := httptest.NewRecorder()
+		if _, err := app.render(rr, req, page, nil); err != nil {
+			t.Fatal(err)
+		}
+		return rr.Body.String()
+	}
+
+	// the CSS is inlined as is, not escaped into a ZgotmplZ placeholder
+	body := render("about.page.gohtml")
+	if !strings.Contains(body, "<style>main{display:block;color:#333}\n</style>") {
+		t.Errorf("critical CSS not inlined; got %s", body)
+	}
+
+	if _, ok := app.critical.css[app.cacheKey("about.page.gohtml")]; !ok {
+		t.Error("critical CSS not cached with the template")
+	}
+
+	// a page without a CSS file gets no style element
+	if body := render("fragment.page.gohtml"); strings.Contains(body, "<style>") {
+		t.Errorf("unexpected style element; got %s", body)
+	}
+
+	app.clearCache()
+	if len(app.critical.css) != 0 {
+		t.Error("critical CSS not cleared with the template cache")
+	}
+}
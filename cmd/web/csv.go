@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"mime"
+	"net/http"
+)
+
+// renderCSV sends headers and rows as a CSV file download named filename,
+// for exporting the data behind a list page. encoding/csv takes care of
+// quoting cells with commas, quotes or newlines. As with render, the file
+// is built in memory first, so an error leaves the response untouched for
+// the caller to deal with, rather than sending half a file.
+func (app *application) renderCSV(w http.ResponseWriter, filename string, headers []string, rows [][]string) error {
+	var buf bytes.Buffer
+
+	cw := csv.NewWriter(&buf)
+	if len(headers) > 0 {
+		if err := cw.Write(headers); err != nil {
+			return err
+		}
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	_, err := buf.WriteTo(w)
+	return err
+}
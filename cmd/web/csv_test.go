@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/csv"
+	"mime"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestApplication_renderCSV(t *testing.T) {
+	app := newTestApp()
+
+	headers := []string{"Breed", "Notes"}
+	rows := [][]string{
+		{"Beagle", "friendly, loud"},
+		{`Staffordshire "Staffy" Bull Terrier`, "good with kids"},
+		{"Poodle", "line one\nline two"},
+	}
+
+	rr := httptest.NewRecorder()
+	if err := app.renderCSV(rr, "dog breeds.csv", headers, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rr.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("wrong Content-Type; got %q", got)
+	}
+
+	disposition, params, err := mime.ParseMediaType(rr.Header().Get("Content-Disposition"))
+	if err != nil || disposition != "attachment" || params["filename"] != "dog breeds.csv" {
+		t.Errorf("wrong Content-Disposition; got %q", rr.Header().Get("Content-Disposition"))
+	}
+
+	want := "Breed,Notes\n" +
+		"Beagle,\"friendly, loud\"\n" +
+		"\"Staffordshire \"\"Staffy\"\" Bull Terrier\",good with kids\n" +
+		"Poodle,\"line one\nline two\"\n"
+	if rr.Body.String() != want {
+		t.Errorf("wrong CSV;\ngot  %q\nwant %q", rr.Body.String(), want)
+	}
+
+	// and it reads back as the same cells
+	got, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, append([][]string{headers}, rows...)) {
+		t.Errorf("cells changed in the round trip; got %q", got)
+	}
+}
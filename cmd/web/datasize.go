@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+)
+
+// maxSizeDepth bounds how deep estimateSize descends into nested values.
+const maxSizeDepth = 32
+
+// warnLargeData logs a warning when the estimated size of td.Data exceeds
+// app.config.dataSizeWarn bytes. It is a development aid for spotting a
+// handler that stuffs something huge into the data, not a limit: the render
+// goes ahead either way. It does nothing in production or when no threshold
+// is set.
+func (app *application) warnLargeData(t string, td *templateData) {
+	limit := app.config.dataSizeWarn
+	if limit <= 0 || !app.isDevelopment() {
+		return
+	}
+
+	size := estimateSize(reflect.ValueOf(td.Data), limit, 0, make(map[uintptr]bool))
+	if size <= limit {
+		return
+	}
+
+	app.log().LogAttrs(context.Background(), slog.LevelWarn, "template data exceeds size threshold",
+		slog.String("template", t),
+		slog.Int("estimated_bytes", size),
+		slog.Int("threshold", limit),
+	)
+}
+
+// estimateSize roughly estimates the serialised size of v in bytes. It
+// stops counting once the estimate passes limit, so a huge value costs no
+// more to check than one just over the threshold. Pointers already seen are
+// not counted again, which also stops cycles.
+func estimateSize(v reflect.Value, limit, depth int, seen map[uintptr]bool) int {
+	if !v.IsValid() || depth > maxSizeDepth {
+		return 0
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.Len()
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return 8
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		if v.Kind() == reflect.Pointer {
+			if seen[v.Pointer()] {
+				return 0
+			}
+			seen[v.Pointer()] = true
+		}
+		return estimateSize(v.Elem(), limit, depth+1, seen)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Len()
+		}
+		size := 0
+		for i := 0; i < v.Len() && size <= limit; i++ {
+			size += estimateSize(v.Index(i), limit-size, depth+1, seen)
+		}
+		return size
+	case reflect.Map:
+		size := 0
+		iter := v.MapRange()
+		for iter.Next() && size <= limit {
+			size += estimateSize(iter.Key(), limit-size, depth+1, seen)
+			size += estimateSize(iter.Value(), limit-size, depth+1, seen)
+		}
+		return size
+	case reflect.Struct:
+		size := 0
+		for i := 0; i < v.NumField() && size <= limit; i++ {
+			size += estimateSize(v.Field(i), limit-size, depth+1, seen)
+		}
+		return size
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_warnLargeData(t *testing.T) {
+	var buf bytes.Buffer
+
+	app := newTestApp()
+	app.logger = slog.New(slog.NewTextHandler(&buf, nil))
+	app.config.env = envDevelopment
+	app.config.dataSizeWarn = 4096
+
+	breeds := make([]string, 10000)
+	for i := range breeds {
+		breeds[i] = "labrador retriever"
+	}
+
+	render := func(data map[string]any) {
+		req, _ := http.NewRequest("GET", "/about", nil)
+		if _, err := app.render(httptest.NewRecorder(), req, "about.page.gohtml", &templateData{Data: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	render(map[string]any{"breeds": breeds})
+
+	if !strings.Contains(buf.String(), "template data exceeds size threshold") ||
+		!strings.Contains(buf.String(), "template=about.page.gohtml") {
+		t.Errorf("no warning for a large payload; got %q", buf.String())
+	}
+
+	// small data doesn't warn
+	buf.Reset()
+	render(map[string]any{"breeds": breeds[:10]})
+	if buf.Len() != 0 {
+		t.Errorf("unexpected warning: %s", buf.String())
+	}
+
+	// and production never checks
+	app.config.env = envProduction
+	render(map[string]any{"breeds": breeds})
+	if buf.Len() != 0 {
+		t.Errorf("warning logged in production: %s", buf.String())
+	}
+}
+
+func TestEstimateSize_cycle(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	n := &node{Name: "loop"}
+	n.Next = n
+
+	app := application{logger: slog.New(slog.DiscardHandler)}
+	app.config.dataSizeWarn = 1
+	app.config.env = envDevelopment
+
+	// must terminate
+	app.warnLargeData("cycle.page.gohtml", &templateData{Data: map[string]any{"n": n}})
+}
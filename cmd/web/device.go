@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// Device classes, as given to templates in .Data.Device.
+const (
+	deviceDesktop = "desktop"
+	deviceMobile  = "mobile"
+	deviceTablet  = "tablet"
+)
+
+// DeviceDetector classifies the device a request came from as one of
+// deviceDesktop, deviceMobile or deviceTablet.
+type DeviceDetector func(r *http.Request) string
+
+// device returns the device class of r, using app.detectDevice if one is
+// set. Without a request, for instance when rendering outside of one, the
+// device is deviceDesktop.
+func (app *application) device(r *http.Request) string {
+	if r == nil {
+		return deviceDesktop
+	}
+	if app.detectDevice != nil {
+		return app.detectDevice(r)
+	}
+	return detectDevice(r.UserAgent())
+}
+
+// detectDevice makes a coarse guess at the device class from a User-Agent
+// string. It is deliberately simple: anything it doesn't recognise is a
+// desktop. Tablets are checked first, as Android tablets and iPads also
+// mention mobile platforms.
+func detectDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case strings.Contains(ua, "ipad"),
+		strings.Contains(ua, "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return deviceTablet
+	case strings.Contains(ua, "mobi"),
+		strings.Contains(ua, "iphone"),
+		strings.Contains(ua, "ipod"),
+		strings.Contains(ua, "android"):
+		return deviceMobile
+	default:
+		return deviceDesktop
+	}
+}
+
+// renderPartial executes the partial name from tmpl with data, preferring
+// the variant for device. It is the partial template function:
+//
+//	{{partial "nav" .}}
+//
+// renders partials/nav.mobile.partial.gohtml for a mobile device if it
+// exists, and partials/nav.partial.gohtml otherwise.
+func renderPartial(tmpl *template.Template, device, name string, data any) (template.HTML, error) {
+	candidates := []string{
+		name + "." + device + ".partial.gohtml",
+		name + ".partial.gohtml",
+	}
+
+	for _, candidate := range candidates {
+		if tmpl.Lookup(candidate) == nil {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, candidate, data); err != nil {
+			return "", err
+		}
+		return template.HTML(buf.String()), nil
+	}
+
+	return "", fmt.Errorf("partial %q not found", name)
+}
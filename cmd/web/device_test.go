@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDetectDevice(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"iphone", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148", deviceMobile},
+		{"android phone", "Mozilla/5.0 (Linux; Android 14; Pixel 8) Chrome/120.0 Mobile Safari/537.36", deviceMobile},
+		{"android tablet", "Mozilla/5.0 (Linux; Android 14; SM-X710) Chrome/120.0 Safari/537.36", deviceTablet},
+		{"ipad", "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X)", deviceTablet},
+		{"desktop", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0 Safari/537.36", deviceDesktop},
+		{"empty", "", deviceDesktop},
+	}
+
+	for _, tt := range tests {
+		if got := detectDevice(tt.userAgent); got != tt.want {
+			t.Errorf("%s: got %q, wanted %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplication_renderDevicePartial(t *testing.T) {
+	app := newTestApp()
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"mobile variant", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148", "<nav>mobile nav</nav>\n\n<main>mobile</main>"},
+		{"desktop fallback", "Mozilla/5.0 (X11; Linux x86_64) Firefox/121.0", "<nav>desktop nav</nav>\n\n<main>desktop</main>"},
+		{"tablet fallback", "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X)", "<nav>desktop nav</nav>\n\n<main>tablet</main>"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/device", nil)
+		req.Header.Set("User-Agent", tt.userAgent)
+		rr := httptest.NewRecorder()
+
+		if _, err := app.render(rr, req, "device.page.gohtml", nil); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if !strings.Contains(rr.Body.String(), tt.want) {
+			t.Errorf("%s: wanted %q in %q", tt.name, tt.want, rr.Body.String())
+		}
+	}
+}
+
+func TestApplication_detectDeviceOverride(t *testing.T) {
+	app := newTestApp()
+	app.detectDevice = func(*http.Request) string { return deviceMobile }
+
+	req, _ := http.NewRequest("GET", "/device", nil)
+	rr := httptest.NewRecorder()
+
+	if _, err := app.render(rr, req, "device.page.gohtml", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(rr.Body.String(), "mobile nav") {
+		t.Errorf("override not used: %q", rr.Body.String())
+	}
+}
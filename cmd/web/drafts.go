@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	draftSuffix = ".draft.gohtml"
+	roleEditor  = "editor"
+)
+
+// errDraftNotFound is returned by render when a draft template is requested
+// by someone who may not see it. They get a 404, as if it didn't exist.
+var errDraftNotFound = fmt.Errorf("draft template: %w", errNotFound)
+
+// isDraft reports whether t is an unpublished template.
+func isDraft(t string) bool {
+	return strings.HasSuffix(t, draftSuffix)
+}
+
+// userRole returns the role of the user making the request. Users remembered
+// by the RememberMe middleware whose ids are listed in app.config.editors are
+// editors; everyone else has no role.
+func (app *application) userRole(r *http.Request) string {
+	userID, ok := rememberedUser(r)
+	if ok && slices.Contains(app.config.editors, userID) {
+		return roleEditor
+	}
+	return ""
+}
+
+// canRender reports whether the template t may be rendered for r. Drafts are
+// only available to editors.
+func (app *application) canRender(r *http.Request, t string) bool {
+	return !isDraft(t) || app.userRole(r) == roleEditor
+}
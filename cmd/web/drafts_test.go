@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_renderDraft(t *testing.T) {
+	app := newTestApp()
+	app.config.useCache = true
+	app.config.editors = []string{"7"}
+
+	// an editor sees the draft, which also puts it in the cache
+	req, _ := http.NewRequest("GET", "/about?draft", nil)
+	editorReq := req.WithContext(context.WithValue(req.Context(), rememberedUserKey, "7"))
+	rr := httptest.NewRecorder()
+
+	if _, err := app.render(rr, editorReq, "about.draft.gohtml", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rr.Body.String(), "about draft") {
+		t.Errorf("editor did not see the draft; got %s", rr.Body.String())
+	}
+
+	// anonymous users and users who aren't editors get a 404, even though
+	// the draft is cached
+	otherReq := req.WithContext(context.WithValue(req.Context(), rememberedUserKey, "8"))
+
+	for name, r := range map[string]*http.Request{"anonymous": req, "not an editor": otherReq} {
+		rr = httptest.NewRecorder()
+
+		if _, err := app.render(rr, r, "about.draft.gohtml", nil); err != errDraftNotFound {
+			t.Errorf("%s: wrong error; got %v", name, err)
+		}
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s: wrong response code; got %d, wanted 404", name, rr.Code)
+		}
+		if strings.Contains(rr.Body.String(), "about draft") {
+			t.Errorf("%s: draft content was served", name)
+		}
+	}
+}
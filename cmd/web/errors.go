@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Errors handlers can return for the ErrorResponder to present.
+var (
+	errNotFound  = errors.New("not found")
+	errForbidden = errors.New("forbidden")
+)
+
+// validationError reports bad input from the client.
+type validationError struct {
+	Field   string
+	Message string
+}
+
+func (e *validationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ParseError reports a template which failed to parse, which is a mistake
+// in the template itself rather than in the data it was given.
+type ParseError struct {
+	Template string
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	return "parsing template " + e.Template + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// ExecError reports a template which parsed but failed while executing,
+// often because of the data it was given.
+type ExecError struct {
+	Template string
+	Err      error
+}
+
+func (e *ExecError) Error() string {
+	return "executing template " + e.Template + ": " + e.Err.Error()
+}
+
+func (e *ExecError) Unwrap() error { return e.Err }
+
+// appHandler is a handler which returns its errors instead of writing them,
+// leaving the response to an ErrorResponder.
+type appHandler func(w http.ResponseWriter, r *http.Request) error
+
+// errorMapping presents errors matched by match with status and template.
+type errorMapping struct {
+	match    func(error) bool
+	status   int
+	template string
+}
+
+// ErrorResponder maps errors returned by handlers to a status code and an
+// error page. Mappings are tried in the order they were added; errors which
+// match none get a 500 and the fallback template.
+type ErrorResponder struct {
+	app      *application
+	mappings []errorMapping
+	fallback string
+}
+
+// newErrorResponder returns an ErrorResponder for the application's known
+// errors.
+func newErrorResponder(app *application) *ErrorResponder {
+	er := &ErrorResponder{app: app, fallback: "error.page.gohtml"}
+
+	er.Map(errNotFound, http.StatusNotFound, "not-found.page.gohtml")
+	er.Map(errForbidden, http.StatusForbidden, "error.page.gohtml")
+	er.MapFunc(func(err error) bool {
+		var ve *validationError
+		return errors.As(err, &ve)
+	}, http.StatusUnprocessableEntity, "error.page.gohtml")
+	er.MapFunc(func(err error) bool {
+		var pe *ParseError
+		var ee *ExecError
+		return errors.As(err, &pe) || errors.As(err, &ee)
+	}, http.StatusInternalServerError, "error.page.gohtml")
+
+	return er
+}
+
+// Map presents errors for which errors.Is(err, target) holds with status
+// and template.
+func (er *ErrorResponder) Map(target error, status int, template string) {
+	er.MapFunc(func(err error) bool { return errors.Is(err, target) }, status, template)
+}
+
+// MapFunc presents errors for which match returns true with status and
+// template. It is how error types are mapped, with errors.As.
+func (er *ErrorResponder) MapFunc(match func(error) bool, status int, template string) {
+	er.mappings = append(er.mappings, errorMapping{match: match, status: status, template: template})
+}
+
+// Lookup returns the status and template used to present err.
+func (er *ErrorResponder) Lookup(err error) (int, string) {
+	for _, m := range er.mappings {
+		if m.match(err) {
+			return m.status, m.template
+		}
+	}
+	return http.StatusInternalServerError, er.fallback
+}
+
+// Respond writes the error page for err. The error itself is logged but
+// never shown to the client.
+func (er *ErrorResponder) Respond(w http.ResponseWriter, r *http.Request, err error) {
+	status, template := er.Lookup(err)
+	if status >= http.StatusInternalServerError {
+		er.app.log().Error("handling request", "path", r.URL.Path, "err", err)
+	}
+
+	td := &templateData{Data: map[string]any{
+		"Status":     status,
+		"StatusText": http.StatusText(status),
+	}}
+
+	_, _ = er.app.render(&statusResponseWriter{ResponseWriter: w, status: status}, r, template, td)
+}
+
+// Handle adapts h to an http.HandlerFunc which presents any error h returns.
+func (er *ErrorResponder) Handle(h appHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			er.Respond(w, r, err)
+		}
+	}
+}
+
+// statusResponseWriter sends status instead of 200 when the body is
+// written without an explicit call to WriteHeader. The writer it wraps is
+// available through Unwrap, for http.ResponseController, flusherOf and
+// pusherOf.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusResponseWriter) WriteHeader(status int) {
+	sw.wroteHeader = true
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusResponseWriter) Write(b []byte) (int, error) {
+	if !sw.wroteHeader {
+		sw.WriteHeader(sw.status)
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// responseStatus returns the status a response written to w without a call
+// to WriteHeader is sent with.
+func responseStatus(w http.ResponseWriter) int {
+	if sw, ok := w.(*statusResponseWriter); ok {
+		return sw.status
+	}
+	return http.StatusOK
+}
+
+// flusherOf returns the http.Flusher w is, or wraps.
+func flusherOf(w http.ResponseWriter) (http.Flusher, bool) {
+	for {
+		if f, ok := w.(http.Flusher); ok {
+			return f, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil, false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// pusherOf returns the http.Pusher w is, or wraps.
+func pusherOf(w http.ResponseWriter) (http.Pusher, bool) {
+	for {
+		if p, ok := w.(http.Pusher); ok {
+			return p, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil, false
+		}
+		w = u.Unwrap()
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorResponder(t *testing.T) {
+	app := newTestApp()
+	er := newErrorResponder(app)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{"not found", errNotFound, http.StatusNotFound, "<main>not found</main>"},
+		{"wrapped not found", fmt.Errorf("loading breed: %w", errNotFound), http.StatusNotFound, "<main>not found</main>"},
+		{"forbidden", errForbidden, http.StatusForbidden, "<main>error 403</main>"},
+		{"validation", &validationError{Field: "breed", Message: "required"}, http.StatusUnprocessableEntity, "<main>error 422</main>"},
+		{"unknown", errors.New("database on fire"), http.StatusInternalServerError, "<main>error 500</main>"},
+	}
+
+	for _, tt := range tests {
+		handler := er.Handle(func(w http.ResponseWriter, r *http.Request) error {
+			return tt.err
+		})
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s: wrong status; got %d, wanted %d", tt.name, rr.Code, tt.wantStatus)
+		}
+		if !strings.Contains(rr.Body.String(), tt.wantBody) {
+			t.Errorf("%s: wrong page; got %s", tt.name, rr.Body.String())
+		}
+		if tt.wantStatus == http.StatusInternalServerError && strings.Contains(rr.Body.String(), tt.err.Error()) {
+			t.Errorf("%s: error message leaked to the client", tt.name)
+		}
+	}
+}
+
+// brokenLoader serves a template with a syntax error in place of
+// broken.page.gohtml, and everything else from disk.
+type brokenLoader struct{}
+
+func (brokenLoader) Load(path string) ([]byte, error) {
+	if strings.HasSuffix(path, "broken.page.gohtml") {
+		return []byte(`{{template "base" .}`), nil
+	}
+	return diskLoader{}.Load(path)
+}
+
+func TestApplication_renderErrorTypes(t *testing.T) {
+	app := newTestApp()
+	app.loader = brokenLoader{}
+
+	if err := app.SetTemplate("bad-data.page.gohtml", `{{.Data.Breed.Name}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	rr := httptest.NewRecorder()
+	_, err := app.render(rr, req, "broken.page.gohtml", nil)
+	if body := rr.Body.String(); rr.Code != http.StatusInternalServerError || strings.Contains(body, "broken.page.gohtml") {
+		t.Errorf("syntax error: wanted a generic 500; got %d %q", rr.Code, body)
+	}
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Template != "broken.page.gohtml" {
+		t.Errorf("syntax error: wanted a ParseError for broken.page.gohtml, got %#v", err)
+	}
+	var ee *ExecError
+	if errors.As(err, &ee) {
+		t.Error("syntax error reported as an ExecError")
+	}
+
+	rr = httptest.NewRecorder()
+	_, err = app.render(rr, req, "bad-data.page.gohtml", &templateData{Data: map[string]any{"Breed": "beagle"}})
+	if body := rr.Body.String(); rr.Code != http.StatusInternalServerError || strings.Contains(body, ".Data.Breed") {
+		t.Errorf("bad data: wanted a generic 500; got %d %q", rr.Code, body)
+	}
+	if !errors.As(err, &ee) || ee.Template != "bad-data.page.gohtml" {
+		t.Errorf("bad data: wanted an ExecError for bad-data.page.gohtml, got %#v", err)
+	}
+	if errors.As(err, &pe) {
+		t.Error("bad data reported as a ParseError")
+	}
+	if errors.Unwrap(err) == nil {
+		t.Error("ExecError doesn't unwrap to its cause")
+	}
+
+	if err := app.SetTemplate("overlay.page.gohtml", `{{if}}`); !errors.As(err, &pe) {
+		t.Errorf("SetTemplate: wanted a ParseError, got %#v", err)
+	}
+
+	// handlers returning them get the generic error page
+	er := newErrorResponder(app)
+	for _, err := range []error{pe, ee} {
+		if status, template := er.Lookup(fmt.Errorf("rendering: %w", err)); status != http.StatusInternalServerError || template != "error.page.gohtml" {
+			t.Errorf("%T: wrong mapping; got %d %s", err, status, template)
+		}
+	}
+}
+
+func TestErrorResponder_notModified(t *testing.T) {
+	app := newTestApp()
+	er := newErrorResponder(app)
+
+	handler := er.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		return errNotFound
+	})
+
+	// whatever the client has cached, an error page keeps its status
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", "*")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("wrong status; got %d, wanted 404", rr.Code)
+	}
+	if etag := rr.Header().Get("ETag"); etag != "" {
+		t.Errorf("error page was tagged: %s", etag)
+	}
+}
+
+func TestStatusResponseWriter_unwrap(t *testing.T) {
+	app := newTestApp()
+	app.config.push = true
+	app.manifest = &assetManifest{Critical: map[string][]string{
+		"about.page.gohtml": {"/static/js/site.js"},
+	}}
+
+	req, _ := http.NewRequest("GET", "/about", nil)
+
+	// push is found behind the wrapper
+	pusher := &fakePusher{ResponseRecorder: httptest.NewRecorder()}
+	if _, err := app.render(&statusResponseWriter{ResponseWriter: pusher, status: http.StatusOK}, req, "about.page.gohtml", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(pusher.pushed) != 1 {
+		t.Errorf("wrapped writer lost push support; pushed %v", pusher.pushed)
+	}
+
+	// and so is flushing, with the wrapper's status still sent
+	td := &templateData{Data: map[string]any{"title": "Dashboard", "rows": []string{"a"}}}
+	flusher := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	sw := &statusResponseWriter{ResponseWriter: flusher, status: http.StatusAccepted}
+	if _, err := app.renderProgressive(sw, req, "progressive.page.gohtml", td, "top", "rest"); err != nil {
+		t.Fatal(err)
+	}
+	if len(flusher.flushes) != 2 || flusher.Code != http.StatusAccepted {
+		t.Errorf("wrapped writer lost flush support; %d flushes, status %d", len(flusher.flushes), flusher.Code)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// contentETag returns a strong ETag for a page body, derived only from the
+// bytes themselves so every instance behind a load balancer computes the
+// same tag for the same page. The gzipped representation gets its own tag,
+// as a cache must not confuse the two.
+//
+// The tag is only as stable as the output: a page whose data varies between
+// renders (a timestamp, a random variant, a CSRF token) gets a new tag each
+// time and is never answered with a 304.
+func contentETag(body []byte, gzipped bool) string {
+	sum := sha256.Sum256(body)
+	tag := hex.EncodeToString(sum[:16])
+	if gzipped {
+		tag += "-gzip"
+	}
+	return `"` + tag + `"`
+}
+
+// notModified reports whether r is a GET or HEAD whose If-None-Match
+// matches etag. If-None-Match uses weak comparison, so a W/ prefix on either
+// side is ignored.
+func notModified(r *http.Request, etag string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
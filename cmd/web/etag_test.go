@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApplication_renderETag(t *testing.T) {
+	render := func(app *application, ifNoneMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/about", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		_, _ = app.render(rr, req, "about.page.gohtml", nil)
+		return rr
+	}
+
+	// two instances, one with caching on, agree on the tag
+	a, b := newTestApp(), newTestApp()
+	b.config.useCache = true
+
+	etagA := render(a, "").Header().Get("ETag")
+	etagB := render(b, "").Header().Get("ETag")
+	if etagA == "" || etagA != etagB {
+		t.Fatalf("ETags differ between instances: %q and %q", etagA, etagB)
+	}
+
+	// so a tag from one validates against the other
+	rr := render(b, etagA)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("wanted 304 for a matching If-None-Match; got %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("304 sent a body: %q", rr.Body.String())
+	}
+
+	if rr := render(b, `W/`+etagA+`, "other"`); rr.Code != http.StatusNotModified {
+		t.Errorf("wanted 304 for a weak match in a list; got %d", rr.Code)
+	}
+	if rr := render(b, `"stale"`); rr.Code != http.StatusOK {
+		t.Errorf("wanted 200 for a stale tag; got %d", rr.Code)
+	}
+
+	// the gzipped page is a different representation
+	b.config.gzip = true
+	req, _ := http.NewRequest("GET", "/about", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	_, _ = b.render(rr, req, "about.page.gohtml", nil)
+	if got := rr.Header().Get("ETag"); got == etagA {
+		t.Errorf("gzipped page has the same ETag as the plain one: %q", got)
+	}
+}
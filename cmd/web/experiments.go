@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	experimentCookiePrefix = "exp_"
+	experimentCookieTTL    = 90 * 24 * time.Hour
+)
+
+// variantsKey is the request context key holding the visitor's experiment
+// assignments.
+const variantsKey contextKey = "variants"
+
+// experiment is an A/B test. Visitors are assigned one of its variants with
+// probability proportional to the variant's weight.
+type experiment struct {
+	Name     string    `json:"name"`
+	Variants []variant `json:"variants"`
+}
+
+type variant struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// loadExperiments reads experiment definitions from the JSON file at path:
+//
+//	[{"name": "hero", "variants": [{"name": "small", "weight": 3}, {"name": "large", "weight": 1}]}]
+//
+// An experiment without a name, with a negative weight or whose weights add
+// up to zero is an error: nobody could be assigned a variant of it.
+func loadExperiments(path string) ([]experiment, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var experiments []experiment
+	if err := json.Unmarshal(b, &experiments); err != nil {
+		return nil, err
+	}
+
+	for _, e := range experiments {
+		if err := e.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return experiments, nil
+}
+
+// validate reports an experiment visitors can't be assigned to.
+func (e experiment) validate() error {
+	if e.Name == "" {
+		return errors.New("experiment has no name")
+	}
+
+	total := 0
+	for _, v := range e.Variants {
+		if v.Weight < 0 {
+			return fmt.Errorf("experiment %q: variant %q has a negative weight", e.Name, v.Name)
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		return fmt.Errorf("experiment %q: no variant has a weight", e.Name)
+	}
+
+	return nil
+}
+
+// has reports whether the experiment has a variant called name.
+func (e experiment) has(name string) bool {
+	for _, v := range e.Variants {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// assign picks a variant at random, by weight.
+func (e experiment) assign() string {
+	total := 0
+	for _, v := range e.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return ""
+	}
+
+	n := rand.IntN(total)
+	for _, v := range e.Variants {
+		if n < v.Weight {
+			return v.Name
+		}
+		n -= v.Weight
+	}
+	return ""
+}
+
+// Experiments is middleware which assigns the visitor a variant of every
+// configured experiment. Assignments are kept in a cookie per experiment so
+// they stick across requests; a missing or unknown value gets a fresh
+// assignment. Templates see them as .Data.Variant, a map from experiment
+// name to variant:
+//
+//	{{if eq (index .Data.Variant "hero") "large"}}...{{end}}
+func (app *application) Experiments(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(app.config.experiments) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		variants := make(map[string]string, len(app.config.experiments))
+
+		for _, e := range app.config.experiments {
+			name := experimentCookiePrefix + e.Name
+
+			if cookie, err := r.Cookie(name); err == nil && e.has(cookie.Value) {
+				variants[e.Name] = cookie.Value
+				continue
+			}
+
+			assigned := e.assign()
+			variants[e.Name] = assigned
+
+			http.SetCookie(w, &http.Cookie{
+				Name:     name,
+				Value:    assigned,
+				Path:     "/",
+				Expires:  time.Now().Add(experimentCookieTTL),
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		ctx := context.WithValue(r.Context(), variantsKey, variants)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// experimentVariants returns the assignments made by Experiments, if any.
+func experimentVariants(r *http.Request) map[string]string {
+	variants, _ := r.Context().Value(variantsKey).(map[string]string)
+	return variants
+}
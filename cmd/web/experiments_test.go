@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplication_Experiments(t *testing.T) {
+	app := application{
+		config: appConfig{experiments: []experiment{{
+			Name:     "hero",
+			Variants: []variant{{Name: "small", Weight: 3}, {Name: "large", Weight: 1}},
+		}}},
+	}
+
+	var got string
+	handler := app.Experiments(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = app.defaultData(r)["Variant"].(map[string]string)["hero"]
+	}))
+
+	visit := func(cookies []*http.Cookie) (string, []*http.Cookie) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return got, rr.Result().Cookies()
+	}
+
+	// one visitor keeps their variant across requests
+	first, cookies := visit(nil)
+	if len(cookies) != 1 {
+		t.Fatalf("wrong number of cookies set; got %d", len(cookies))
+	}
+	for i := 0; i < 20; i++ {
+		again, set := visit(cookies)
+		if again != first {
+			t.Fatalf("variant changed from %s to %s", first, again)
+		}
+		if len(set) != 0 {
+			t.Fatal("assignment cookie set again for a known visitor")
+		}
+	}
+
+	// an unknown variant in the cookie is replaced
+	bad, set := visit([]*http.Cookie{{Name: "exp_hero", Value: "huge"}})
+	if bad == "huge" || len(set) != 1 {
+		t.Errorf("invalid cookie value kept: %s", bad)
+	}
+
+	// over many new visitors the split follows the weights
+	const visitors = 10000
+	counts := map[string]int{}
+	for i := 0; i < visitors; i++ {
+		v, _ := visit(nil)
+		counts[v]++
+	}
+
+	share := float64(counts["small"]) / visitors
+	if math.Abs(share-0.75) > 0.03 {
+		t.Errorf("wrong split; got %.3f small, wanted about 0.75 (%v)", share, counts)
+	}
+}
+
+func TestLoadExperiments(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{"valid", `[{"name": "hero", "variants": [{"name": "small", "weight": 3}, {"name": "large", "weight": 0}]}]`, false},
+		{"all zero weights", `[{"name": "hero", "variants": [{"name": "small", "weight": 0}, {"name": "large", "weight": 0}]}]`, true},
+		{"no variants", `[{"name": "hero"}]`, true},
+		{"negative weight", `[{"name": "hero", "variants": [{"name": "small", "weight": 2}, {"name": "large", "weight": -1}]}]`, true},
+		{"no name", `[{"variants": [{"name": "small", "weight": 1}]}]`, true},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "experiments.json")
+		if err := os.WriteFile(path, []byte(tt.json), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := loadExperiments(path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, wanted error: %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
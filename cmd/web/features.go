@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/tsawler/toolbox"
+)
+
+// errFeatureDisabled is returned by render for a template whose feature
+// flag is off.
+var errFeatureDisabled = fmt.Errorf("feature disabled: %w", errNotFound)
+
+// FeatureGate decides whether a template may be rendered, so whole pages can
+// be rolled out gradually. render asks it before looking the template up,
+// and serves a 404 when it says no.
+type FeatureGate interface {
+	Allow(template string) bool
+}
+
+// flagGate is a FeatureGate backed by a set of flags which can be flipped at
+// runtime. Templates without a flag are allowed.
+type flagGate struct {
+	mu       sync.RWMutex
+	disabled map[string]bool
+}
+
+// newFlagGate returns a flagGate with the given templates switched off.
+func newFlagGate(disabled []string) *flagGate {
+	g := &flagGate{disabled: make(map[string]bool)}
+	for _, t := range disabled {
+		if t != "" {
+			g.disabled[t] = true
+		}
+	}
+	return g
+}
+
+// Allow reports whether template t is switched on.
+func (g *flagGate) Allow(t string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return !g.disabled[t]
+}
+
+// Set switches template t on or off.
+func (g *flagGate) Set(t string, enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if enabled {
+		delete(g.disabled, t)
+	} else {
+		g.disabled[t] = true
+	}
+}
+
+// SetFeature is the admin endpoint which switches the page template in the
+// URL on or off, e.g. POST /admin/features/about.page.gohtml?enabled=false.
+// Only editors may use it.
+func (app *application) SetFeature(w http.ResponseWriter, r *http.Request) error {
+	if app.userRole(r) != roleEditor {
+		return errForbidden
+	}
+
+	gate, ok := app.features.(*flagGate)
+	if !ok {
+		return errNotFound
+	}
+
+	t := chi.URLParam(r, "template")
+
+	enabled, err := strconv.ParseBool(r.URL.Query().Get("enabled"))
+	if err != nil {
+		return &validationError{Field: "enabled", Message: "must be true or false"}
+	}
+
+	gate.Set(t, enabled)
+
+	var tools toolbox.Tools
+	return tools.WriteJSON(w, http.StatusOK, map[string]any{"template": t, "enabled": enabled})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// countingLoader is a templateLoader which counts the files it loads.
+type countingLoader struct {
+	loads int
+}
+
+func (l *countingLoader) Load(path string) ([]byte, error) {
+	l.loads++
+	return diskLoader{}.Load(path)
+}
+
+func TestApplication_renderFeatureGate(t *testing.T) {
+	app := newTestApp()
+	loader := &countingLoader{}
+	app.loader = loader
+	app.features = newFlagGate([]string{"about.page.gohtml"})
+
+	req, _ := http.NewRequest("GET", "/about", nil)
+
+	// gated off: 404, and the template isn't parsed
+	rr := httptest.NewRecorder()
+	_, _ = app.render(rr, req, "about.page.gohtml", nil)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("wrong response code; got %d, wanted 404", rr.Code)
+	}
+	if loader.loads != 0 {
+		t.Errorf("gated template was loaded %d times", loader.loads)
+	}
+
+	// switched on at runtime through the admin endpoint
+	app.config.editors = []string{"7"}
+	mux := app.routes()
+
+	flip := func(userID, enabled string) int {
+		req, _ := http.NewRequest("POST", "/admin/features/about.page.gohtml?enabled="+enabled, nil)
+		req = req.WithContext(context.WithValue(req.Context(), rememberedUserKey, userID))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := flip("8", "true"); code != http.StatusForbidden {
+		t.Errorf("non-editor flipped a flag; got %d", code)
+	}
+	if code := flip("7", "maybe"); code != http.StatusUnprocessableEntity {
+		t.Errorf("bad flag value accepted; got %d", code)
+	}
+	if code := flip("7", "true"); code != http.StatusOK {
+		t.Fatalf("editor could not flip a flag; got %d", code)
+	}
+
+	// gated on: normal render
+	rr = httptest.NewRecorder()
+	_, _ = app.render(rr, req, "about.page.gohtml", nil)
+
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "<main>about</main>") {
+		t.Errorf("enabled page not rendered; got %d %s", rr.Code, rr.Body.String())
+	}
+}
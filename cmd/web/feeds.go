@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// feedRefreshAt is how far into its TTL a cached feed is regenerated in
+// the background, so that it is replaced before it goes stale.
+const feedRefreshAt = 0.8
+
+// feedExpiresAt is how many TTLs old a cached feed may get, when
+// regenerating it keeps failing or nobody asks for it, before it is no
+// longer served and the next request has to wait for a new one.
+const feedExpiresAt = 2
+
+// errFeedPanicked is the error of a feed whose generator panicked.
+var errFeedPanicked = errors.New("feed generator panicked")
+
+// feedCache holds generated feeds, sitemaps and the like, keyed by path.
+// The zero value is ready to use.
+type feedCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedFeed
+}
+
+// cachedFeed is one feed. ready is closed once the first generation has
+// finished, successfully or not; body, err and generated are only written
+// under feedCache.mu after that.
+type cachedFeed struct {
+	ready      chan struct{}
+	body       []byte
+	err        error
+	generated  time.Time
+	refreshing bool
+}
+
+// renderCachedFeed returns the output of gen for path, generating it at
+// most once per ttl however many requests ask for it. Only the very first
+// request waits for gen; others arriving meanwhile wait with it. After
+// that, once ttl*feedRefreshAt has passed, the next request starts a
+// background regeneration and, like every request until it finishes, gets
+// the previous output: possibly slightly stale, but never slow. A failed
+// regeneration is logged and the previous output kept, to be retried by a
+// later request; a failed first generation is returned, and retried by the
+// next request. Output older than ttl*feedExpiresAt is never served: the
+// next request generates the feed afresh, as if it were the first.
+func (app *application) renderCachedFeed(path string, ttl time.Duration, gen func() ([]byte, error)) ([]byte, error) {
+	fc := &app.feeds
+
+	fc.mu.Lock()
+	if fc.entries == nil {
+		fc.entries = make(map[string]*cachedFeed)
+	}
+	entry, ok := fc.entries[path]
+	if ok && !entry.generated.IsZero() && time.Since(entry.generated) >= ttl*feedExpiresAt {
+		ok = false
+	}
+	if !ok {
+		entry = &cachedFeed{ready: make(chan struct{})}
+		fc.entries[path] = entry
+	}
+	fc.mu.Unlock()
+
+	// the first request generates the feed, the others wait for it
+	if !ok {
+		return fc.generate(path, entry, gen)
+	}
+	<-entry.ready
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if entry.err != nil {
+		return nil, entry.err
+	}
+
+	due := time.Since(entry.generated) >= time.Duration(float64(ttl)*feedRefreshAt)
+	if due && !entry.refreshing {
+		entry.refreshing = true
+		app.refreshFeed(path, entry, gen)
+	}
+
+	return entry.body, nil
+}
+
+// generate runs gen for the new entry, stored under path. entry is ready
+// once generate returns, and removed again if gen failed, even if gen
+// panics; the panic is passed on, and the requests waiting for entry get
+// errFeedPanicked.
+func (fc *feedCache) generate(path string, entry *cachedFeed, gen func() ([]byte, error)) (body []byte, err error) {
+	defer close(entry.ready)
+	defer func() {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+
+		entry.body, entry.err, entry.generated = body, err, time.Now()
+		if err != nil && fc.entries[path] == entry {
+			delete(fc.entries, path)
+		}
+	}()
+
+	err = errFeedPanicked
+	return gen()
+}
+
+// refreshFeed regenerates entry in the background. A panic in gen is
+// recovered, as nothing else would recover it outside the request, and
+// treated like an error.
+func (app *application) refreshFeed(path string, entry *cachedFeed, gen func() ([]byte, error)) {
+	app.background.Add(1)
+	go func() {
+		defer app.background.Done()
+
+		body, err := recoverFeed(gen)
+
+		app.feeds.mu.Lock()
+		defer app.feeds.mu.Unlock()
+
+		entry.refreshing = false
+		if err != nil {
+			app.log().Error("regenerating feed, serving the previous version", "path", path, "err", err)
+			return
+		}
+		entry.body, entry.generated = body, time.Now()
+	}()
+}
+
+// recoverFeed calls gen, returning a panic as an error wrapping
+// errFeedPanicked.
+func recoverFeed(gen func() ([]byte, error)) (body []byte, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%w: %v", errFeedPanicked, p)
+		}
+	}()
+
+	return gen()
+}
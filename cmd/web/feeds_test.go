@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestApplication_renderCachedFeed(t *testing.T) {
+	app := newTestApp()
+
+	var runs atomic.Int32
+	gen := func() ([]byte, error) {
+		n := runs.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return []byte(fmt.Sprintf("<rss>v%d</rss>", n)), nil
+	}
+
+	const ttl = 200 * time.Millisecond
+
+	fetch := func(want string) {
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				body, err := app.renderCachedFeed("/feed.xml", ttl, gen)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if string(body) != want {
+					t.Errorf("got %q, wanted %q", body, want)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	// concurrent first requests share a single generation
+	fetch("<rss>v1</rss>")
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("generator ran %d times, wanted 1", n)
+	}
+
+	// near the end of the TTL, requests still get the current version
+	// straight away while one background refresh replaces it
+	time.Sleep(ttl * 9 / 10)
+	fetch("<rss>v1</rss>")
+	app.background.Wait()
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("generator ran %d times after the refresh point, wanted 2", n)
+	}
+
+	fetch("<rss>v2</rss>")
+	if n := runs.Load(); n != 2 {
+		t.Errorf("generator ran %d times within the window, wanted 2", n)
+	}
+}
+
+func TestApplication_renderCachedFeedErrors(t *testing.T) {
+	app := newTestApp()
+
+	fail := true
+	gen := func() ([]byte, error) {
+		if fail {
+			return nil, errors.New("database on fire")
+		}
+		return []byte("<urlset/>"), nil
+	}
+
+	if _, err := app.renderCachedFeed("/sitemap.xml", time.Hour, gen); err == nil {
+		t.Fatal("expected the first generation's error")
+	}
+
+	// a failed first generation isn't cached
+	fail = false
+	body, err := app.renderCachedFeed("/sitemap.xml", time.Hour, gen)
+	if err != nil || string(body) != "<urlset/>" {
+		t.Errorf("got %q, %v after the generator recovered", body, err)
+	}
+}
+
+func TestApplication_renderCachedFeedPanics(t *testing.T) {
+	app := newTestApp()
+
+	var panics atomic.Bool
+	panics.Store(true)
+	started := make(chan struct{})
+	gen := func() ([]byte, error) {
+		if panics.Load() {
+			close(started)
+			time.Sleep(20 * time.Millisecond)
+			panic("template exploded")
+		}
+		return []byte("<rss/>"), nil
+	}
+
+	// a request waiting on a generation that panics gets an error
+	waiter := make(chan error)
+	go func() {
+		defer func() { recover() }()
+		app.renderCachedFeed("/feed.xml", time.Hour, gen)
+	}()
+	<-started
+	go func() {
+		_, err := app.renderCachedFeed("/feed.xml", time.Hour, gen)
+		waiter <- err
+	}()
+	select {
+	case err := <-waiter:
+		if !errors.Is(err, errFeedPanicked) {
+			t.Errorf("waiting request got %v, wanted errFeedPanicked", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiting request still blocked after the generator panicked")
+	}
+
+	// and the failed generation isn't kept
+	panics.Store(false)
+	body, err := app.renderCachedFeed("/feed.xml", time.Hour, gen)
+	if err != nil || string(body) != "<rss/>" {
+		t.Fatalf("got %q, %v after the panic", body, err)
+	}
+
+	// a panicking background refresh is recovered, keeping the old version
+	panics.Store(true)
+	started = make(chan struct{})
+	app.feeds.mu.Lock()
+	app.feeds.entries["/feed.xml"].generated = time.Now().Add(-59 * time.Minute)
+	app.feeds.mu.Unlock()
+	body, err = app.renderCachedFeed("/feed.xml", time.Hour, gen)
+	app.background.Wait()
+	if err != nil || string(body) != "<rss/>" {
+		t.Errorf("got %q, %v while refreshing", body, err)
+	}
+}
+
+func TestApplication_renderCachedFeedExpiry(t *testing.T) {
+	app := newTestApp()
+
+	var runs atomic.Int32
+	gen := func() ([]byte, error) {
+		n := runs.Add(1)
+		if n == 2 {
+			return nil, errors.New("database on fire")
+		}
+		return []byte(fmt.Sprintf("<rss>v%d</rss>", n)), nil
+	}
+
+	const ttl = 50 * time.Millisecond
+
+	if _, err := app.renderCachedFeed("/feed.xml", ttl, gen); err != nil {
+		t.Fatal(err)
+	}
+
+	// the refresh fails, so the first version is kept...
+	time.Sleep(ttl)
+	body, err := app.renderCachedFeed("/feed.xml", ttl, gen)
+	app.background.Wait()
+	if err != nil || string(body) != "<rss>v1</rss>" {
+		t.Fatalf("got %q, %v after the failed refresh", body, err)
+	}
+
+	// ...but not past twice the TTL
+	time.Sleep(ttl)
+	body, err = app.renderCachedFeed("/feed.xml", ttl, gen)
+	if err != nil || string(body) != "<rss>v3</rss>" {
+		t.Errorf("got %q, %v after expiry, wanted <rss>v3</rss>", body, err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/net/html"
+)
+
+// renderFragmentByID renders the page template t in full, then writes only
+// the element whose id is elementID, including the element itself, as
+// htmx's hx-select would pick out. If the page has no such element the
+// error wraps errNotFound and nothing is written.
+func (app *application) renderFragmentByID(w http.ResponseWriter, r *http.Request, t string, td *templateData, elementID string) error {
+	buf, err := app.execute(r, t, td)
+	if err != nil {
+		return err
+	}
+
+	fragment, ok, err := elementByID(buf.Bytes(), elementID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("element #%s in %s: %w", elementID, t, errNotFound)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(fragment)))
+	w.Header().Set("Cache-Control", app.cacheControlFor(t))
+	_, err = w.Write(fragment)
+	return err
+}
+
+// elementByID returns the element with the given id in doc, rendered from
+// its start tag to its end tag. doc is parsed as a browser would, so the
+// element ends where a browser would end it, including elements like <p>,
+// <li> and <td> whose end tags may be left out. The markup returned is
+// html.Render's, which may differ in quoting and case from doc's.
+func elementByID(doc []byte, id string) ([]byte, bool, error) {
+	root, err := html.Parse(bytes.NewReader(doc))
+	if err != nil {
+		return nil, false, err
+	}
+
+	node := findByID(root, id)
+	if node == nil {
+		return nil, false, nil
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, node); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// findByID returns the first element below n, in document order, whose id
+// is id.
+func findByID(n *html.Node, id string) *html.Node {
+	if n.Type == html.ElementNode {
+		for _, attr := range n.Attr {
+			if attr.Namespace == "" && attr.Key == "id" && attr.Val == id {
+				return n
+			}
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findByID(c, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
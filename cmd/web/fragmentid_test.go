@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestApplication_renderFragmentByID(t *testing.T) {
+	app := newTestApp()
+	td := &templateData{Data: map[string]any{"Item": "Beagle"}}
+
+	req, _ := http.NewRequest("GET", "/swap", nil)
+	rr := httptest.NewRecorder()
+	if err := app.renderFragmentByID(rr, req, "swap.page.gohtml", td, "cart"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<div id="cart" class="cart"><div class="item">Beagle</div><img src="/dog.png"/><div>total</div></div>`
+	if got := rr.Body.String(); got != want {
+		t.Errorf("wrong fragment;\ngot  %s\nwant %s", got, want)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("wrong Content-Type; got %q", ct)
+	}
+	if cl := rr.Header().Get("Content-Length"); cl != strconv.Itoa(len(want)) {
+		t.Errorf("wrong Content-Length; got %q, wanted %d", cl, len(want))
+	}
+
+	rr = httptest.NewRecorder()
+	err := app.renderFragmentByID(rr, req, "swap.page.gohtml", td, "missing")
+	if !errors.Is(err, errNotFound) {
+		t.Errorf("wanted errNotFound for a missing id; got %v", err)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("wrote %q for a missing id", rr.Body.String())
+	}
+}
+
+func TestElementByID(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		id   string
+		want string
+	}{
+		{"void", `<p><img id=dog src="/dog.png"> woof</p>`, "dog", `<img id="dog" src="/dog.png"/>`},
+		{"single quotes", `<ul><li id='x'>one</li><li>two</li></ul>`, "x", `<li id="x">one</li>`},
+		{"same name nested", `<div id="a"><div><div></div></div></div><div>b</div>`, "a", `<div id="a"><div><div></div></div></div>`},
+		{"upper case", `<DIV ID="a">x</DIV>`, "a", `<div id="a">x</div>`},
+		{"implicit li end", `<ul><li id="x">one<li>two</ul>`, "x", `<li id="x">one</li>`},
+		{"implicit p end", `<p id="a">one<p>two`, "a", `<p id="a">one</p>`},
+		{"implicit td end", `<table><tr><td id="c">1<td>2</table>`, "c", `<td id="c">1</td>`},
+		{"in a comment", `<!-- <b id="a">no</b> --><i id="a">yes</i>`, "a", `<i id="a">yes</i>`},
+		{"in a script", `<script>"<b id='a'>"</script><i id="a">yes</i>`, "a", `<i id="a">yes</i>`},
+	}
+
+	for _, tt := range tests {
+		got, ok, err := elementByID([]byte(tt.doc), tt.id)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if !ok || string(got) != tt.want {
+			t.Errorf("%s: got %q, %v; wanted %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// fragmentCache holds the rendered output of template fragments, keyed by
+// page template and fragment name. It backs the cachefragment template function, which lets a
+// page reuse expensive, rarely-changing parts (a sidebar, say) while the rest
+// of the page renders fresh on every request:
+//
+//	{{cachefragment "sidebar" 300 .}}
+//
+//	{{define "sidebar"}}...{{end}}
+//
+// The first call executes the "sidebar" template and caches its output for
+// 300 seconds; calls within that window return the cached HTML. Pages which
+// each define their own "sidebar" get an entry each. Entries are shared by
+// every request for a page, so a cached fragment must not depend on
+// per-request data. The zero value is ready to use.
+type fragmentCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedFragment
+
+	// group makes concurrent misses for an entry execute it only once
+	group singleflight.Group
+}
+
+type cachedFragment struct {
+	html    template.HTML
+	expires time.Time
+}
+
+// render returns the cached output of the fragment called name in the page
+// template tmpl, executing it against data if there is no live entry. The
+// lock is not held while the fragment executes, so fragments may themselves
+// contain cached fragments.
+func (fc *fragmentCache) render(tmpl *template.Template, name string, ttl time.Duration, data any) (template.HTML, error) {
+	key := tmpl.Name() + "#" + name
+
+	if html, ok := fc.lookup(key); ok {
+		return html, nil
+	}
+
+	v, err, _ := fc.group.Do(key, func() (any, error) {
+		// another caller may have filled the entry since the lookup above
+		if html, ok := fc.lookup(key); ok {
+			return html, nil
+		}
+
+		fragment := tmpl.Lookup(name)
+		if fragment == nil {
+			return nil, fmt.Errorf("cachefragment: no template named %q", name)
+		}
+
+		var buf bytes.Buffer
+		if err := fragment.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+
+		// the fragment was executed by html/template, so it is already escaped
+		html := template.HTML(buf.String())
+
+		fc.mu.Lock()
+		if fc.entries == nil {
+			fc.entries = make(map[string]cachedFragment)
+		}
+		fc.entries[key] = cachedFragment{html: html, expires: time.Now().Add(ttl)}
+		fc.mu.Unlock()
+
+		return html, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return v.(template.HTML), nil
+}
+
+// lookup returns the live entry for key, if there is one.
+func (fc *fragmentCache) lookup(key string) (template.HTML, bool) {
+	fc.mu.Lock()
+	entry, ok := fc.entries[key]
+	fc.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expires) {
+		return entry.html, true
+	}
+	return "", false
+}
+
+// clear removes every entry.
+func (fc *fragmentCache) clear() {
+	fc.mu.Lock()
+	fc.entries = nil
+	fc.mu.Unlock()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// callCounter counts how many times a template calls Next. If delay is set,
+// each call takes that long.
+type callCounter struct {
+	mu    sync.Mutex
+	n     int
+	delay time.Duration
+}
+
+func (c *callCounter) Next() int {
+	time.Sleep(c.delay)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.n++
+	return c.n
+}
+
+func TestApplication_cachefragment(t *testing.T) {
+	app := newTestApp()
+	counter := &callCounter{}
+
+	for i, dynamic := range []string{"first", "second"} {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/fragment", nil)
+
+		app.render(rr, req, "fragment.page.gohtml", &templateData{
+			Data: map[string]any{"dynamic": dynamic, "counter": counter},
+		})
+
+		body := rr.Body.String()
+		if !strings.Contains(body, "dynamic "+dynamic) {
+			t.Errorf("render %d: dynamic content not rendered fresh; got %s", i, body)
+		}
+		if !strings.Contains(body, "<aside>sidebar 1</aside>") {
+			t.Errorf("render %d: cached fragment not in output; got %s", i, body)
+		}
+	}
+
+	if counter.n != 1 {
+		t.Errorf("fragment executed %d times, wanted 1", counter.n)
+	}
+}
+
+func TestFragmentCache_expiry(t *testing.T) {
+	app := newTestApp()
+	tmpl, err := app.buildTemplateFromDisk("fragment.page.gohtml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fc fragmentCache
+	counter := &callCounter{}
+	data := &templateData{Data: map[string]any{"counter": counter}}
+
+	_, _ = fc.render(tmpl, "sidebar", time.Nanosecond, data)
+	time.Sleep(time.Millisecond)
+	html, err := fc.render(tmpl, "sidebar", time.Nanosecond, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if html != "<aside>sidebar 2</aside>" {
+		t.Errorf("expired fragment was not executed again; got %s", html)
+	}
+
+	if _, err := fc.render(tmpl, "missing", time.Minute, data); err == nil {
+		t.Error("expected an error for an unknown fragment")
+	}
+}
+
+func TestFragmentCache_perPage(t *testing.T) {
+	app := newTestApp()
+	page := mustTemplate(t, app, "fragment.page.gohtml")
+	other := template.Must(template.New("other.page.gohtml").Parse(`{{define "sidebar"}}<aside>other</aside>{{end}}`))
+
+	var fc fragmentCache
+	data := &templateData{Data: map[string]any{"counter": &callCounter{}}}
+
+	for _, tt := range []struct {
+		tmpl *template.Template
+		want template.HTML
+	}{
+		{page, "<aside>sidebar 1</aside>"},
+		{other, "<aside>other</aside>"},
+		{page, "<aside>sidebar 1</aside>"},
+	} {
+		html, err := fc.render(tt.tmpl, "sidebar", time.Minute, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if html != tt.want {
+			t.Errorf("%s: wrong fragment; got %s, wanted %s", tt.tmpl.Name(), html, tt.want)
+		}
+	}
+
+	// clearing the cache makes the fragment execute again
+	fc.clear()
+	if html, _ := fc.render(page, "sidebar", time.Minute, data); html != "<aside>sidebar 2</aside>" {
+		t.Errorf("fragment was still cached after clear; got %s", html)
+	}
+}
+
+func TestFragmentCache_concurrentMisses(t *testing.T) {
+	app := newTestApp()
+	tmpl := mustTemplate(t, app, "fragment.page.gohtml")
+
+	var fc fragmentCache
+	counter := &callCounter{delay: 20 * time.Millisecond}
+	data := &templateData{Data: map[string]any{"counter": counter}}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := fc.render(tmpl, "sidebar", time.Minute, data); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if counter.n != 1 {
+		t.Errorf("fragment executed %d times, wanted 1", counter.n)
+	}
+}
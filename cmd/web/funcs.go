@@ -0,0 +1,43 @@
+package main
+
+import "html/template"
+
+// RenderOption adjusts a single call to render or renderTo.
+type RenderOption func(*renderOptions)
+
+// renderOptions is the result of applying a render's RenderOptions.
+type renderOptions struct {
+	funcs template.FuncMap
+}
+
+// WithFuncs makes funcs available to templates for one render only, taking
+// precedence over functions of the same name, such as a formatter used
+// just for previews:
+//
+//	app.render(w, r, "breed.page.gohtml", td, WithFuncs(template.FuncMap{"price": previewPrice}))
+//
+// The render works on its own copy of the template, so the cached template
+// and concurrent renders never see them. As a template can only call
+// functions known when it was parsed, each name must either be an existing
+// template function or have been declared with AddFunc.
+func WithFuncs(funcs template.FuncMap) RenderOption {
+	return func(o *renderOptions) {
+		if o.funcs == nil {
+			o.funcs = make(template.FuncMap, len(funcs))
+		}
+		for name, fn := range funcs {
+			o.funcs[name] = fn
+		}
+	}
+}
+
+// AddFunc adds fn to the functions available to every template, under
+// name. It must be called before templates are parsed, so at startup,
+// as templates parsed earlier don't know about it; a function only meant
+// to be given with WithFuncs can be declared with a placeholder here.
+func (app *application) AddFunc(name string, fn any) {
+	if app.funcs == nil {
+		app.funcs = make(template.FuncMap)
+	}
+	app.funcs[name] = fn
+}
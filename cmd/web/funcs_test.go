@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestApplication_renderWithFuncs(t *testing.T) {
+	app := newTestApp()
+	app.config.useCache = true
+	app.AddFunc("label", func(s string) string { return "global " + s })
+
+	if err := app.SetTemplate("label.page.gohtml", `{{label .Data.Breed}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(opts ...RenderOption) string {
+		req, _ := http.NewRequest("GET", "/", nil)
+		rr := httptest.NewRecorder()
+		if _, err := app.render(rr, req, "label.page.gohtml", &templateData{Data: map[string]any{"Breed": "beagle"}}, opts...); err != nil {
+			t.Error(err)
+		}
+		return rr.Body.String()
+	}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			want := "global beagle"
+			var opts []RenderOption
+			if i%2 == 0 {
+				want = fmt.Sprintf("call %d beagle", i)
+				opts = append(opts, WithFuncs(template.FuncMap{
+					"label": func(s string) string { return fmt.Sprintf("call %d %s", i, s) },
+				}))
+			}
+
+			if got := render(opts...); got != want {
+				t.Errorf("render %d: got %q, wanted %q", i, got, want)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the shared template still has the global function
+	if got := render(); got != "global beagle" {
+		t.Errorf("per-call function leaked; got %q", got)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+)
+
+// RenderGolden renders the template t with td as a plain GET of "/" would,
+// defaults and providers included, and returns the output. It exists so
+// tests can compare a template's output against a golden file; see
+// assertGolden in the tests.
+func (app *application) RenderGolden(t string, td *templateData) ([]byte, error) {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := app.execute(r, t, td)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
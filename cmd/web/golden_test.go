@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// scrubber normalises volatile parts of rendered output before it is
+// compared with a golden file.
+type scrubber func([]byte) []byte
+
+var timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?( ?(Z|[+-]\d{2}:?\d{2}))?( [A-Z]{3,4})?`)
+
+// scrubTimestamps replaces anything which looks like a timestamp.
+func scrubTimestamps(b []byte) []byte {
+	return timestampPattern.ReplaceAll(b, []byte("<timestamp>"))
+}
+
+// assertGolden compares got, after applying scrubbers, with
+// testdata/<name>.golden. With -update, the file is written instead.
+func assertGolden(t *testing.T, name string, got []byte, scrubbers ...scrubber) {
+	t.Helper()
+
+	for _, scrub := range scrubbers {
+		got = scrub(got)
+	}
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestApplication_RenderGolden(t *testing.T) {
+	app := newTestApp()
+
+	got, err := app.RenderGolden("golden.page.gohtml", &templateData{
+		Data: map[string]any{
+			"Breed":   "Beagle",
+			"Updated": time.Now().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertGolden(t, "golden.page", got, scrubTimestamps)
+}
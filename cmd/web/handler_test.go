@@ -14,7 +14,7 @@ func TestApplication_GetAllDogBreedsJSON(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// create the handler
-	handler := http.HandlerFunc(testApp.GetAllDogBreedJSON)
+	handler := http.HandlerFunc(testApp.GetAllDogBreedsJSON)
 
 	// serve the handler
 	handler.ServeHTTP(rr, req)
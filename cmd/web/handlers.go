@@ -9,13 +9,21 @@ import (
 	"github.com/tsawler/toolbox"
 )
 
-func (app *application) ShowHome(w http.ResponseWriter, r *http.Request) {
-	app.render(w, "home.page.gohtml", nil)
-}
-
-func (app *application) ShowPage(w http.ResponseWriter, r *http.Request) {
+func (app *application) ShowPage(w http.ResponseWriter, r *http.Request) error {
 	page := chi.URLParam(r, "page")
-	app.render(w, fmt.Sprintf("%s.page.gohtml", page), nil)
+
+	// editors can preview the unpublished version of a page with ?draft
+	t := fmt.Sprintf("%s.page.gohtml", page)
+	if r.URL.Query().Has("draft") {
+		t = fmt.Sprintf("%s.draft.gohtml", page)
+	}
+
+	if !app.templateExists(t) {
+		return errNotFound
+	}
+
+	_, _ = app.render(w, r, t, nil)
+	return nil
 }
 
 func (app *application) CreateDogFromFactory(w http.ResponseWriter, r *http.Request) {
@@ -28,10 +36,6 @@ func (app *application) CreateCatFromFactory(w http.ResponseWriter, r *http.Requ
 	_ = t.WriteJSON(w, http.StatusOK, pets.NewPet("cat"))
 }
 
-func (app *application) TestPatterns(w http.ResponseWriter, r *http.Request) {
-	app.render(w, "test.page.gohtml", nil)
-}
-
 func (app *application) CreateDogFromAbstractFactory(w http.ResponseWriter, r *http.Request) {
 	var t toolbox.Tools
 	dog, err := pets.NewPetFromAbstractFactory("dog")
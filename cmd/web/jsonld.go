@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"log/slog"
+)
+
+// jsonLD marshals v to compact JSON for a JSON-LD script element. It is the
+// jsonld template function:
+//
+//	<script type="application/ld+json">{{jsonld .Data.Breed}}</script>
+//
+// encoding/json escapes <, > and & so the data can't close the script
+// element early. A nil value, or one which can't be marshalled, emits
+// nothing rather than failing the page; the latter is logged to the
+// default logger, as template functions have no application.
+func jsonLD(v any) template.JS {
+	if v == nil {
+		return ""
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("marshalling JSON-LD", "err", err)
+		return ""
+	}
+
+	return template.JS(b)
+}
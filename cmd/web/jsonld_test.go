@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestApplication_jsonld(t *testing.T) {
+	app := newTestApp()
+
+	src := `<script type="application/ld+json">{{jsonld .Data.Breed}}</script><script type="application/ld+json">{{jsonld .Data.Missing}}</script>`
+	if err := app.SetTemplate("jsonld.page.gohtml", src); err != nil {
+		t.Fatal(err)
+	}
+
+	type breed struct {
+		Context string `json:"@context"`
+		Type    string `json:"@type"`
+		Name    string `json:"name"`
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	_, err := app.render(rr, req, "jsonld.page.gohtml", &templateData{Data: map[string]any{
+		"Breed": breed{Context: "https://schema.org", Type: "Thing", Name: "Beagle </script>"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scripts := regexp.MustCompile(`<script type="application/ld\+json">(.*?)</script>`).FindAllStringSubmatch(rr.Body.String(), -1)
+	if len(scripts) != 2 {
+		t.Fatalf("wanted 2 script elements; got %s", rr.Body.String())
+	}
+
+	var got breed
+	if err := json.Unmarshal([]byte(scripts[0][1]), &got); err != nil {
+		t.Fatalf("invalid JSON-LD %q: %v", scripts[0][1], err)
+	}
+	if got.Type != "Thing" || got.Name != "Beagle </script>" {
+		t.Errorf("wrong data; got %+v", got)
+	}
+
+	// nil emits nothing
+	if scripts[1][1] != "" {
+		t.Errorf("nil value emitted %q", scripts[1][1])
+	}
+
+	// nor does something which can't be marshalled
+	if got := jsonLD(make(chan int)); got != "" {
+		t.Errorf("unmarshallable value emitted %q", got)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// lazyValue wraps a func() any or func() (any, error) from the template
+// data so that it runs at most once per render, and only if the template
+// asks for it with the lazy function.
+type lazyValue struct {
+	once  sync.Once
+	fn    func() (any, error)
+	value any
+}
+
+// get runs the function on first use and returns its result. A failing
+// function is logged and yields nil, so the template sees no data rather
+// than failing the page.
+func (l *lazyValue) get() any {
+	l.once.Do(func() {
+		value, err := l.fn()
+		if err != nil {
+			slog.Error("evaluating lazy template data", "err", err)
+			return
+		}
+		l.value = value
+	})
+	return l.value
+}
+
+// wrapLazy replaces the functions among td's values with lazyValues. td
+// must be the data for a single render, as composeData returns, since the
+// results are kept for as long as td is.
+func wrapLazy(td *templateData) {
+	for key, value := range td.Data {
+		switch fn := value.(type) {
+		case func() any:
+			td.Data[key] = &lazyValue{fn: func() (any, error) { return fn(), nil }}
+		case func() (any, error):
+			td.Data[key] = &lazyValue{fn: fn}
+		}
+	}
+}
+
+// lazy is the lazy template function. It evaluates data which was given
+// as a function, so that expensive data is only computed when the branch
+// using it runs:
+//
+//	{{if .Data.ShowRelated}}{{range lazy .Data.Related}}...{{end}}{{end}}
+//
+// However often it is used in a render, the function runs once. Other
+// values are returned as they are.
+func lazy(v any) any {
+	switch v := v.(type) {
+	case *lazyValue:
+		return v.get()
+	case func() any:
+		return v()
+	case func() (any, error):
+		value, err := v()
+		if err != nil {
+			slog.Error("evaluating lazy template data", "err", err)
+			return nil
+		}
+		return value
+	default:
+		return v
+	}
+}
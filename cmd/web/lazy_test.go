@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApplication_renderLazy(t *testing.T) {
+	app := newTestApp()
+
+	src := `{{if .Data.Show}}{{range lazy .Data.Related}}<li>{{.}}</li>{{end}} {{len (lazy .Data.Related)}}{{end}}{{lazy .Data.Broken}}`
+	if err := app.SetTemplate("lazy.page.gohtml", src); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	render := func(show bool) string {
+		td := &templateData{Data: map[string]any{
+			"Show": show,
+			"Related": func() any {
+				calls++
+				return []string{"Beagle", "Basset"}
+			},
+			"Broken": func() (any, error) { return nil, errors.New("database on fire") },
+		}}
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		rr := httptest.NewRecorder()
+		if _, err := app.render(rr, req, "lazy.page.gohtml", td); err != nil {
+			t.Fatal(err)
+		}
+		return rr.Body.String()
+	}
+
+	// a branch which isn't taken doesn't compute its data
+	if got := render(false); got != "" {
+		t.Errorf("wrong output; got %q", got)
+	}
+	if calls != 0 {
+		t.Errorf("lazy data computed %d times when unused", calls)
+	}
+
+	// and one which is computes it once, however often it's used
+	if got := render(true); got != "<li>Beagle</li><li>Basset</li> 2" {
+		t.Errorf("wrong output; got %q", got)
+	}
+	if calls != 1 {
+		t.Errorf("lazy data computed %d times, wanted 1", calls)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// utf8BOM is the byte order mark some editors write at the start of UTF-8
+// files. html/template would copy it verbatim into the page.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// templateLoader reads the source of a template file. Templates must be
+// UTF-8 encoded; a leading byte order mark is stripped by parseTemplateFiles,
+// but no other encodings are converted.
+type templateLoader interface {
+	Load(path string) ([]byte, error)
+}
+
+// diskLoader is the default templateLoader, reading templates from disk.
+type diskLoader struct{}
+
+// Load reads the file at path.
+func (diskLoader) Load(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// templateLoader returns the loader configured for the application, falling
+// back to reading from disk.
+func (app *application) templateLoader() templateLoader {
+	if app.loader != nil {
+		return app.loader
+	}
+	return diskLoader{}
+}
+
+// parseTemplateFiles works like template.ParseFiles: each file is parsed into
+// a template named after its base name, and the file whose base name matches
+// tmpl's name becomes tmpl itself. Unlike ParseFiles, sources are read
+// through the application's loader and have any UTF-8 BOM removed, and
+// syntax errors are returned as a *ParseError.
+func (app *application) parseTemplateFiles(tmpl *template.Template, files ...string) (*template.Template, error) {
+	loader := app.templateLoader()
+
+	for _, file := range files {
+		src, err := loader.Load(file)
+		if err != nil {
+			return nil, err
+		}
+		src = bytes.TrimPrefix(src, utf8BOM)
+
+		name := filepath.Base(file)
+		t := tmpl
+		if name != tmpl.Name() {
+			t = tmpl.New(name)
+		}
+
+		if _, err := t.Parse(string(src)); err != nil {
+			return nil, &ParseError{Template: tmpl.Name(), Err: err}
+		}
+	}
+
+	return tmpl, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApplication_parseTemplateFilesStripsBOM(t *testing.T) {
+	app := newTestApp()
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/bom", nil)
+
+	app.render(rr, req, "bom.page.gohtml", nil)
+
+	body := rr.Body.Bytes()
+	if !bytes.Contains(body, []byte("<main>bom</main>")) {
+		t.Fatalf("page not rendered; got %s", body)
+	}
+	if bytes.Contains(body, utf8BOM) {
+		t.Errorf("rendered output contains a BOM: %q", body[:16])
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"container/list"
+	"html/template"
+	"strings"
+	"sync"
+)
+
+// lruCache is a TemplateCache holding at most perTenant templates for each
+// tenant, evicting a tenant's least recently used template to make room
+// for another of its own. A tenant is an application's cache prefix (the
+// part of the key before tenantSeparator; see cacheKey), so one tenant with
+// many templates can't push another's out. Keys without a prefix share the
+// default tenant "".
+type lruCache struct {
+	mu        sync.Mutex
+	perTenant int
+	tenants   map[string]*tenantCache
+}
+
+// tenantCache is the templates of one tenant, most recently used first.
+type tenantCache struct {
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is a template in a tenantCache's list.
+type lruEntry struct {
+	key  string
+	tmpl *template.Template
+}
+
+// newLRUCache returns an lruCache holding up to perTenant templates per
+// tenant; less than 1 means no limit.
+func newLRUCache(perTenant int) *lruCache {
+	return &lruCache{perTenant: perTenant, tenants: make(map[string]*tenantCache)}
+}
+
+// tenantOf returns the tenant a key belongs to.
+func tenantOf(key string) string {
+	tenant, _, found := strings.Cut(key, tenantSeparator)
+	if !found {
+		return ""
+	}
+	return tenant
+}
+
+// Get returns the template stored under key, marking it as recently used.
+func (c *lruCache) Get(key string) (*template.Template, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tc, ok := c.tenants[tenantOf(key)]
+	if !ok {
+		return nil, false
+	}
+	el, ok := tc.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	tc.order.MoveToFront(el)
+	return el.Value.(*lruEntry).tmpl, true
+}
+
+// Set stores tmpl under key, evicting the tenant's least recently used
+// template if the tenant is full.
+func (c *lruCache) Set(key string, tmpl *template.Template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tenant := tenantOf(key)
+	tc, ok := c.tenants[tenant]
+	if !ok {
+		tc = &tenantCache{order: list.New(), entries: make(map[string]*list.Element)}
+		c.tenants[tenant] = tc
+	}
+
+	if el, ok := tc.entries[key]; ok {
+		el.Value.(*lruEntry).tmpl = tmpl
+		tc.order.MoveToFront(el)
+		return
+	}
+
+	tc.entries[key] = tc.order.PushFront(&lruEntry{key: key, tmpl: tmpl})
+
+	if c.perTenant > 0 && tc.order.Len() > c.perTenant {
+		oldest := tc.order.Back()
+		tc.order.Remove(oldest)
+		delete(tc.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Clear removes every entry whose key starts with prefix.
+func (c *lruCache) Clear(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for tenant, tc := range c.tenants {
+		for key, el := range tc.entries {
+			if strings.HasPrefix(key, prefix) {
+				tc.order.Remove(el)
+				delete(tc.entries, key)
+			}
+		}
+		if tc.order.Len() == 0 {
+			delete(c.tenants, tenant)
+		}
+	}
+}
+
+// ClearTenant removes all of tenant's templates, leaving other tenants'.
+func (c *lruCache) ClearTenant(tenant string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.tenants, tenant)
+}
+
+// tenantClearer is implemented by caches which can clear a single tenant,
+// including the default one.
+type tenantClearer interface {
+	ClearTenant(tenant string)
+}
+
+// ClearTenant removes the templates cached for tenant, an application's
+// cache prefix, from the application's cache, which may be shared with
+// other tenants. The default tenant "", the applications without a prefix,
+// can only be cleared in caches implementing tenantClearer, as its keys
+// have no prefix to match.
+func (app *application) ClearTenant(tenant string) {
+	switch c := app.cache.(type) {
+	case nil:
+	case tenantClearer:
+		c.ClearTenant(tenant)
+	default:
+		if tenant != "" {
+			c.Clear(tenant + tenantSeparator)
+		}
+	}
+}
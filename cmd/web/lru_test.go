@@ -0,0 +1,101 @@
+package main
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestLRUCache_tenants(t *testing.T) {
+	shared := newLRUCache(2)
+
+	newTenant := func(prefix string) *application {
+		app := newTestApp()
+		app.cache = shared
+		app.config.useCache = true
+		app.config.cachePrefix = prefix
+		return app
+	}
+	a, b := newTenant("a"), newTenant("b")
+	single := newTestApp()
+	single.cache = shared
+
+	mustTemplate(t, b, "about.page.gohtml")
+	mustTemplate(t, b, "fragment.page.gohtml")
+	mustTemplate(t, single, "about.page.gohtml")
+
+	// A filling up evicts only its own least recently used template
+	mustTemplate(t, a, "about.page.gohtml")
+	mustTemplate(t, a, "fragment.page.gohtml")
+	a.cachedTemplate("about.page.gohtml")
+	mustTemplate(t, a, "breeds.page.gohtml")
+
+	if _, ok := a.cachedTemplate("fragment.page.gohtml"); ok {
+		t.Error("least recently used template of A not evicted")
+	}
+	for _, name := range []string{"about.page.gohtml", "breeds.page.gohtml"} {
+		if _, ok := a.cachedTemplate(name); !ok {
+			t.Errorf("A lost %s", name)
+		}
+	}
+	for _, name := range []string{"about.page.gohtml", "fragment.page.gohtml"} {
+		if _, ok := b.cachedTemplate(name); !ok {
+			t.Errorf("B lost %s to A's evictions", name)
+		}
+	}
+
+	// reloading the default tenant leaves B alone
+	if err := single.reloadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	single.clearCache()
+	if _, ok := b.cachedTemplate("about.page.gohtml"); !ok {
+		t.Error("clearing the default tenant's cache removed B's templates")
+	}
+	mustTemplate(t, single, "about.page.gohtml")
+
+	// clearing A leaves B and the default tenant alone
+	a.ClearTenant("a")
+	if _, ok := a.cachedTemplate("about.page.gohtml"); ok {
+		t.Error("ClearTenant left A's templates")
+	}
+	if _, ok := b.cachedTemplate("about.page.gohtml"); !ok {
+		t.Error("ClearTenant of A removed B's templates")
+	}
+	if _, ok := single.cachedTemplate("about.page.gohtml"); !ok {
+		t.Error("ClearTenant of A removed the default tenant's templates")
+	}
+
+	// and the default tenant can be cleared on its own
+	single.ClearTenant("")
+	if _, ok := single.cachedTemplate("about.page.gohtml"); ok {
+		t.Error("default tenant not cleared")
+	}
+	if _, ok := b.cachedTemplate("fragment.page.gohtml"); !ok {
+		t.Error("clearing the default tenant removed B's templates")
+	}
+}
+
+func TestMapCache_ClearTenant(t *testing.T) {
+	c := newMapCache()
+	tmpl := template.New("x")
+	c.Set("a|about.page.gohtml", tmpl)
+	c.Set("b|about.page.gohtml", tmpl)
+	c.Set("about.page.gohtml", tmpl)
+	c.Set("a/about.page.gohtml", tmpl)
+
+	app := &application{cache: c}
+	app.ClearTenant("a")
+
+	if _, ok := c.Get("a|about.page.gohtml"); ok {
+		t.Error("tenant a not cleared")
+	}
+	if _, ok := c.Get("b|about.page.gohtml"); !ok {
+		t.Error("tenant b cleared")
+	}
+	if _, ok := c.Get("about.page.gohtml"); !ok {
+		t.Error("default tenant cleared")
+	}
+	if _, ok := c.Get("a/about.page.gohtml"); !ok {
+		t.Error("template in directory a taken for tenant a")
+	}
+}
@@ -1,38 +1,268 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"go-breeders/configuration"
 	
 	"html/template"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	texttemplate "text/template"
 	"time"
 )
 
 const port = ":4000"
 
+const (
+	envDevelopment = "development"
+	envProduction  = "production"
+)
+
 type application struct {
-	templateMap map[string]*template.Template
+	cache       TemplateCache
+	overlays    map[string]*template.Template
+	cacheMu     sync.RWMutex
 	config      appConfig
 	App *configuration.Application
+	loader      templateLoader
+	pages       []page
+	providers   []DataProvider
+	transforms  []Transform
+	errorPages  *ErrorResponder
+	manifest    *assetManifest
+	schemas     dataSchemas
+	features    FeatureGate
+	logger      *slog.Logger
+	stats       renderStats
+	fragments   fragmentCache
+	critical    criticalCSSCache
+	feeds       feedCache
+
+	// build describes the running binary; see currentBuild
+	build buildInfo
+
+	// OnCacheHit and OnCacheMiss, when set, are called with the template
+	// name each time a render finds its template in the cache or has to
+	// build it, for instance to feed metrics
+	OnCacheHit  func(name string)
+	OnCacheMiss func(name string)
+
+	// scopedProviders only run for paths under their prefix; see ProvideFor
+	scopedProviders []scopedProvider
+
+	// detectDevice classifies requests by device; when nil, the device is
+	// guessed from the User-Agent
+	detectDevice DeviceDetector
+
+	// documentTransforms run after transforms and, unlike them, need the
+	// whole page, so progressive renders can't apply them; see
+	// renderProgressive
+	documentTransforms []Transform
+
+	// htmlValidator checks rendered pages in development; see validateHTML
+	htmlValidator HTMLValidator
+
+	// funcs are template functions added with AddFunc
+	funcs template.FuncMap
+
+	// parseSem limits concurrent template parsing; see parseSlots
+	parseSem     chan struct{}
+	parseSemOnce sync.Once
+
+	// textTemplateMap caches the text/template templates used by renderContent
+	textTemplateMap map[string]*texttemplate.Template
+
+	// quit is closed by Shutdown to stop background goroutines, which
+	// register themselves on background.
+	quit         chan struct{}
+	background   sync.WaitGroup
+	shutdownOnce sync.Once
 }
 
 type appConfig struct {
-	useCache bool
-	dsn      string
+	useCache    bool
+	gzip        bool
+	push        bool
+	dsn         string
+	templateDir string
+	renderedDir string
+
+	// cachePrefix scopes the application's keys in a shared TemplateCache
+	cachePrefix string
+
+	// leftDelim and rightDelim replace the {{ and }} action delimiters
+	// when set, e.g. to avoid clashing with Vue templates
+	leftDelim  string
+	rightDelim string
+
+	env         string
+	previewKeys []string
+
+	// breadcrumbLabels gives friendly names to path segments
+	breadcrumbLabels map[string]string
+
+	// themes are the allowed values of .Data.Theme
+	themes       []string
+	defaultTheme string
+
+	// dataSizeWarn is the estimated template data size, in bytes, above
+	// which a warning is logged in development; 0 disables the check
+	dataSizeWarn int
+
+	// parseConcurrency is how many templates may be parsed from disk at
+	// once; 0 means GOMAXPROCS
+	parseConcurrency int
+
+	// parseAttempts is how many times reading and parsing a template is
+	// tried when it fails with a transient error, waiting parseBackoff
+	// before the first retry and twice as long before each one after;
+	// less than 2 means no retries
+	parseAttempts int
+	parseBackoff  time.Duration
+
+	// templateBudget is the largest size, in bytes of template source, a
+	// page may compile to when the cache is built; 0 disables the check.
+	// Pages over budget are logged, or fail the build if
+	// templateBudgetStrict is set.
+	templateBudget       int
+	templateBudgetStrict bool
+
+	// cacheControl classifies templates for the Cache-Control header
+	cacheControl []cacheControlRule
+
+	// rememberSecret signs remember-me tokens; they are ignored when empty
+	rememberSecret []byte
+
+	// editors are the user ids allowed to preview draft templates
+	editors []string
+
+	// experiments are the A/B tests visitors are assigned to
+	experiments []experiment
+
+	// stripComments removes HTML comments from pages in production
+	stripComments bool
+
+	// baseURL is the site's public address, used in the sitemap
+	baseURL string
+
+	// redirectHosts are the external hosts redirect may send visitors to
+	redirectHosts []string
 }
 
 func main() {
 	app := application{
-		templateMap: make(map[string]*template.Template),
+		cache:       newMapCache(),
+		quit:        make(chan struct{}),
+		build:       currentBuild(),
+		config: appConfig{
+			cacheControl: defaultCacheControl,
+			themes:       []string{"light", "dark"},
+			defaultTheme: "light",
+			breadcrumbLabels: map[string]string{
+				"cat-breeds":    "Cat Breeds",
+				"dog-breeds":    "Dog Breeds",
+				"cat-breeders":  "Cat Breeders",
+				"dog-breeders":  "Dog Breeders",
+				"dog-of-month":  "Dog of the Month",
+				"test-patterns": "Design Patterns",
+			},
+		},
 	}
 
 	flag.BoolVar(&app.config.useCache, "cache", false, "Use template cache")
+	cacheSize := flag.Int("cache-size", 0, "Most templates cached per tenant, least recently used evicted first (0 is unlimited)")
+	debug := flag.Bool("debug", false, "Enable debug logging")
+	flag.IntVar(&app.config.dataSizeWarn, "data-size-warn", 1<<20, "Warn when template data is estimated above this many bytes (development only, 0 disables)")
+	reloadOnHUP := flag.Bool("reload-on-hup", false, "Rebuild the template cache on SIGHUP")
+	flag.IntVar(&app.config.parseConcurrency, "parse-concurrency", 0, "Templates parsed from disk at once (default GOMAXPROCS)")
+	flag.IntVar(&app.config.parseAttempts, "parse-attempts", 1, "Attempts at reading a template when the filesystem fails transiently")
+	flag.DurationVar(&app.config.parseBackoff, "parse-backoff", 50*time.Millisecond, "Wait before retrying a template read, doubled for each further retry")
+	flag.IntVar(&app.config.templateBudget, "template-budget", 0, "Largest size in bytes a compiled page may have when the template cache is built (0 disables)")
+	flag.BoolVar(&app.config.templateBudgetStrict, "template-budget-strict", false, "Fail the template cache build, instead of warning, when a page is over budget")
+	flag.BoolVar(&app.config.stripComments, "strip-comments", false, "Remove HTML comments from pages (production only)")
+	validateAMP := flag.Bool("validate-amp", false, "Log AMP violations in rendered pages (development only)")
+	flag.BoolVar(&app.config.gzip, "gzip", false, "Gzip rendered pages for clients which accept it")
+	flag.BoolVar(&app.config.push, "push", false, "Push critical assets from the manifest over HTTP/2")
+	manifest := flag.String("manifest", "", "Path to the static asset manifest")
+	experiments := flag.String("experiments", "", "Path to the A/B experiment definitions")
+	flag.StringVar(&app.config.templateDir, "templates", defaultTemplateDir, "Template directory")
+	flag.StringVar(&app.config.renderedDir, "rendered", "", "Directory of pre-rendered pages served under /rendered/")
+	flag.StringVar(&app.config.leftDelim, "left-delim", "", "Left template action delimiter (default {{)")
+	flag.StringVar(&app.config.rightDelim, "right-delim", "", "Right template action delimiter (default }})")
 	flag.StringVar(&app.config.dsn, "dsn", "mariadb:myverysecretpassword@tcp(localhost:3306)/breeders?parseTime=true&tls=false&collation=utf8_unicode_ci&timeout=5s", "DSN")
+	flag.StringVar(&app.config.baseURL, "base-url", "", "Public URL of the site, for the sitemap (required in production; in development taken from each request)")
+	flag.StringVar(&app.config.env, "env", envProduction, "Environment (development|production)")
+	rememberSecret := flag.String("remember-secret", "", "Secret used to sign remember-me cookies")
+	disabledPages := flag.String("disabled-pages", "", "Comma separated templates switched off by feature flag")
+	editors := flag.String("editors", "", "Comma separated user ids allowed to preview drafts")
+	redirectHosts := flag.String("redirect-hosts", "", "Comma separated external hosts redirects may point to")
+	previewKeys := flag.String("preview-keys", "", "Comma separated query parameters copied into template data (development only)")
 	flag.Parse()
 
+	app.config.rememberSecret = []byte(*rememberSecret)
+
+	if *cacheSize > 0 {
+		app.cache = newLRUCache(*cacheSize)
+	}
+
+	if *experiments != "" {
+		e, err := loadExperiments(*experiments)
+		if err != nil {
+			log.Fatal(err)
+		}
+		app.config.experiments = e
+	}
+
+	if *debug {
+		app.logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		// template functions, which have no application, log here too
+		slog.SetDefault(app.logger)
+	}
+
+	if *manifest != "" {
+		m, err := loadManifest(*manifest)
+		if err != nil {
+			log.Fatal(err)
+		}
+		app.manifest = m
+		app.transforms = append(app.transforms, FingerprintAssets(m))
+	}
+
+	if *validateAMP {
+		app.htmlValidator = AMPValidator
+	}
+
+	if app.config.baseURL == "" && !app.isDevelopment() {
+		log.Fatal("-base-url is required in production")
+	}
+
+	if app.config.stripComments && !app.isDevelopment() {
+		app.documentTransforms = append(app.documentTransforms, StripComments)
+	}
+
+	app.features = newFlagGate(strings.Split(*disabledPages, ","))
+
+	if *editors != "" {
+		app.config.editors = strings.Split(*editors, ",")
+	}
+
+	if *redirectHosts != "" {
+		app.config.redirectHosts = strings.Split(*redirectHosts, ",")
+	}
+
+	if *previewKeys != "" {
+		app.config.previewKeys = strings.Split(*previewKeys, ",")
+	}
+
 	// get database
 	db, err := initMySQLDB(app.config.dsn)
 	if err != nil {
@@ -41,6 +271,25 @@ func main() {
 
 
 	app.App = configuration.New(db)
+	app.errorPages = newErrorResponder(&app)
+
+	if err := app.registerPages(); err != nil {
+		log.Fatal(err)
+	}
+
+	// with caching on, render the registered pages once up front so the
+	// first visitors don't pay for compiling them
+	if app.config.useCache {
+		var routes []warmRoute
+		for _, p := range app.pages {
+			routes = append(routes, warmRoute{T: p.template})
+		}
+		app.WarmRender(routes)
+	}
+
+	if *reloadOnHUP {
+		app.ReloadOnSIGHUP()
+	}
 
 	srv := &http.Server{
 		Addr:              port,
@@ -51,11 +300,44 @@ func main() {
 		WriteTimeout:      30 * time.Second,
 	}
 
-	fmt.Println("Starting web application on port", port)
+	// stop on SIGINT or SIGTERM, letting in-flight requests finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	err = srv.ListenAndServe()
-	if err != nil {
-		log.Fatal(err)
+	go func() {
+		fmt.Println("Starting web application on port", port)
+
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	app.log().Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		app.log().Error("shutting down server", "err", err)
+	}
+
+	if err := app.Shutdown(shutdownCtx); err != nil {
+		app.log().Error("shutting down application", "err", err)
 	}
+}
+
+// log returns the application's structured logger.
+func (app *application) log() *slog.Logger {
+	if app.logger == nil {
+		return slog.Default()
+	}
+	return app.logger
+}
 
+// isDevelopment reports whether the application is running in the development
+// environment. Anything else, including an empty value, is treated as production.
+func (app *application) isDevelopment() bool {
+	return app.config.env == envDevelopment
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+)
+
+// assetManifest describes the fingerprinted static assets. Assets maps each
+// asset's logical path to its fingerprinted one, and Critical lists, per
+// template, the logical paths of the assets a page can't render without:
+//
+//	{
+//	  "assets": {"/static/css/site.css": "/static/css/site.3f2a1c.css"},
+//	  "critical": {"home.page.gohtml": ["/static/css/site.css"]}
+//	}
+type assetManifest struct {
+	Assets   map[string]string   `json:"assets"`
+	Critical map[string][]string `json:"critical"`
+}
+
+// loadManifest reads the asset manifest at path.
+func loadManifest(path string) (*assetManifest, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m assetManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// criticalAssets returns the fingerprinted paths of the critical assets for
+// template t. Assets missing from the manifest's asset list are used as is.
+func (m *assetManifest) criticalAssets(t string) []string {
+	if m == nil {
+		return nil
+	}
+
+	var paths []string
+	for _, asset := range m.Critical[t] {
+		if fingerprinted, ok := m.Assets[asset]; ok {
+			asset = fingerprinted
+		}
+		paths = append(paths, asset)
+	}
+
+	return paths
+}
+
+// pushCriticalAssets pushes the critical assets for template t when server
+// push is enabled and the connection supports it. On HTTP/1.1 w is not, and
+// doesn't wrap, an http.Pusher, and nothing happens.
+func (app *application) pushCriticalAssets(w http.ResponseWriter, t string) {
+	if !app.config.push {
+		return
+	}
+
+	pusher, ok := pusherOf(w)
+	if !ok {
+		return
+	}
+
+	for _, path := range app.manifest.criticalAssets(t) {
+		if err := pusher.Push(path, nil); err != nil {
+			if !errors.Is(err, http.ErrNotSupported) {
+				app.log().Error("pushing asset", "path", path, "err", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// fakePusher is a ResponseRecorder which supports server push, recording
+// the paths pushed.
+type fakePusher struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *fakePusher) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestApplication_renderPushesCriticalAssets(t *testing.T) {
+	app := newTestApp()
+	app.config.push = true
+	app.manifest = &assetManifest{
+		Assets: map[string]string{"/static/css/site.css": "/static/css/site.3f2a1c.css"},
+		Critical: map[string][]string{
+			"about.page.gohtml": {"/static/css/site.css", "/static/js/site.js"},
+		},
+	}
+
+	req, _ := http.NewRequest("GET", "/about", nil)
+
+	w := &fakePusher{ResponseRecorder: httptest.NewRecorder()}
+	if _, err := app.render(w, req, "about.page.gohtml", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/static/css/site.3f2a1c.css", "/static/js/site.js"}
+	if !reflect.DeepEqual(w.pushed, want) {
+		t.Errorf("wrong assets pushed; got %v, wanted %v", w.pushed, want)
+	}
+
+	// a writer without push support, as on HTTP/1.1, still renders
+	rr := httptest.NewRecorder()
+	if _, err := app.render(rr, req, "about.page.gohtml", nil); err != nil || rr.Code != http.StatusOK {
+		t.Errorf("render without push support failed: %v %d", err, rr.Code)
+	}
+
+	// templates without critical assets push nothing
+	w = &fakePusher{ResponseRecorder: httptest.NewRecorder()}
+	_, _ = app.render(w, req, "account.page.gohtml", nil)
+	if len(w.pushed) != 0 {
+		t.Errorf("unexpected pushes: %v", w.pushed)
+	}
+}
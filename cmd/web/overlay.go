@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// SetTemplate registers the template t from the in-memory source src, with
+// the same delimiters and functions as templates read from disk. It takes
+// precedence over a file of the same name, and is used regardless of whether
+// caching is enabled. This is mostly useful in tests, which can define a
+// minimal template inline instead of adding a file.
+func (app *application) SetTemplate(t, src string) error {
+	tmpl, err := app.newTemplate(t).Parse(src)
+	if err != nil {
+		return &ParseError{Template: t, Err: err}
+	}
+
+	app.cacheMu.Lock()
+	if app.overlays == nil {
+		app.overlays = make(map[string]*template.Template)
+	}
+	app.overlays[t] = tmpl
+	app.cacheMu.Unlock()
+
+	return nil
+}
+
+// renderString executes the template t with td and returns the output.
+// Unlike render, no default or provider data is added.
+func (app *application) renderString(t string, td *templateData) (string, error) {
+	tmpl, err := app.getTemplate(t)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err = app.bindRequest(tmpl, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if td == nil {
+		td = &templateData{}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, t, td); err != nil {
+		return "", &ExecError{Template: t, Err: err}
+	}
+
+	return buf.String(), nil
+}
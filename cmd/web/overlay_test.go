@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_SetTemplate(t *testing.T) {
+	app := newTestApp()
+
+	if err := app.SetTemplate("greeting.page.gohtml", `<p>Hello, {{index .Data "name"}}!</p>`); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := app.renderString("greeting.page.gohtml", &templateData{Data: map[string]any{"name": "Fido"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got != "<p>Hello, Fido!</p>" {
+		t.Errorf("wrong output; got %q", got)
+	}
+
+	// disk templates are still available alongside the overlay
+	if _, err := app.renderString("about.page.gohtml", nil); err != nil {
+		t.Errorf("disk template not available: %s", err)
+	}
+
+	// an overlay replaces a template of the same name on disk
+	if err := app.SetTemplate("about.page.gohtml", `<p>overlay</p>`); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/about", nil)
+	app.render(rr, req, "about.page.gohtml", nil)
+
+	if !strings.Contains(rr.Body.String(), "<p>overlay</p>") {
+		t.Errorf("overlay did not take precedence; got %s", rr.Body.String())
+	}
+
+	if err := app.SetTemplate("broken.page.gohtml", `{{if}}`); err == nil {
+		t.Error("expected a parse error")
+	}
+}
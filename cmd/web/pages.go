@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// DataFunc prepares the template data for a registered page. It may return
+// nil data when the page needs none.
+type DataFunc func(r *http.Request) (*templateData, error)
+
+// page maps a route to the template rendered for it.
+type page struct {
+	path     string
+	template string
+	prepare  DataFunc
+	sitemap  sitemapMeta
+}
+
+// registerPages declares the site's pages. Keeping them in one place makes
+// the site map explicit, and RegisterPage catches typos in template names at
+// startup rather than on the first request.
+func (app *application) registerPages() error {
+	pages := []struct {
+		path     string
+		template string
+	}{
+		{"/", "home.page.gohtml"},
+		{"/test-patterns", "test.page.gohtml"},
+	}
+
+	for _, p := range pages {
+		if err := app.RegisterPage(p.path, p.template, nil); err != nil {
+			return err
+		}
+	}
+
+	homePriority := 1.0
+	return app.SetSitemapMeta("/", sitemapMeta{Priority: &homePriority, ChangeFreq: "daily"})
+}
+
+// RegisterPage adds a page to the registry consulted by routes. The template
+// must exist in the template directory, and each path may only be registered
+// once. prepare may be nil.
+func (app *application) RegisterPage(path, templateName string, prepare DataFunc) error {
+	if _, err := app.statTemplate(templateName); err != nil {
+		return fmt.Errorf("register page %s: unknown template %s: %w", path, templateName, err)
+	}
+
+	for _, p := range app.pages {
+		if p.path == path {
+			return fmt.Errorf("register page %s: path already registered for %s", path, p.template)
+		}
+	}
+
+	app.pages = append(app.pages, page{path: path, template: templateName, prepare: prepare})
+
+	return nil
+}
+
+// statTemplate returns the file info of the template t.
+func (app *application) statTemplate(t string) (os.FileInfo, error) {
+	return os.Stat(filepath.Join(app.templateDir(), t))
+}
+
+// templateExists reports whether the template t is in the template
+// directory or was registered with SetTemplate.
+func (app *application) templateExists(t string) bool {
+	app.cacheMu.RLock()
+	_, ok := app.overlays[t]
+	app.cacheMu.RUnlock()
+	if ok {
+		return true
+	}
+
+	_, err := app.statTemplate(t)
+	return err == nil
+}
+
+// pageHandler returns the handler which renders a registered page.
+func (app *application) pageHandler(p page) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var td *templateData
+
+		if p.prepare != nil {
+			var err error
+			td, err = p.prepare(r)
+			if err != nil {
+				app.log().Error("preparing page data", "path", r.URL.Path, "err", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		_, _ = app.render(w, r, p.template, td)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_RegisterPage(t *testing.T) {
+	app := newTestApp()
+
+	if err := app.RegisterPage("/missing", "missing.page.gohtml", nil); err == nil {
+		t.Error("expected an error registering an unknown template")
+	}
+
+	prepare := func(r *http.Request) (*templateData, error) {
+		return &templateData{Data: map[string]any{"dynamic": "prepared"}}, nil
+	}
+
+	if err := app.RegisterPage("/fragment", "fragment.page.gohtml", prepare); err != nil {
+		t.Fatalf("unexpected error registering a page: %s", err)
+	}
+
+	if err := app.RegisterPage("/fragment", "bom.page.gohtml", nil); err == nil {
+		t.Error("expected an error registering a path twice")
+	}
+
+	// the registered page should be served by the router
+	req, _ := http.NewRequest("GET", "/fragment", nil)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("wrong response code; got %d, wanted 200", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "dynamic prepared") {
+		t.Errorf("page data not prepared; got %s", rr.Body.String())
+	}
+}
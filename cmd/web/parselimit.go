@@ -0,0 +1,30 @@
+package main
+
+import (
+	"html/template"
+	"runtime"
+)
+
+// parseSlots returns the semaphore bounding how many templates are read
+// and parsed from disk at once, app.config.parseConcurrency or, if that
+// isn't set, GOMAXPROCS. It is created on first use.
+func (app *application) parseSlots() chan struct{} {
+	app.parseSemOnce.Do(func() {
+		n := app.config.parseConcurrency
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		app.parseSem = make(chan struct{}, n)
+	})
+	return app.parseSem
+}
+
+// parseTemplateLimited is parseTemplate, waiting for a free parse slot
+// first, so a cold start doesn't flood the disk with reads.
+func (app *application) parseTemplateLimited(t, layout string) (*template.Template, error) {
+	slots := app.parseSlots()
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	return app.parseTemplate(t, layout)
+}
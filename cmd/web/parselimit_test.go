@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyLoader records the most page loads it has seen in flight.
+type concurrencyLoader struct {
+	current atomic.Int32
+	peak    atomic.Int32
+}
+
+func (l *concurrencyLoader) Load(path string) ([]byte, error) {
+	if strings.HasSuffix(path, ".page.gohtml") {
+		n := l.current.Add(1)
+		defer l.current.Add(-1)
+
+		for {
+			peak := l.peak.Load()
+			if n <= peak || l.peak.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return diskLoader{}.Load(path)
+}
+
+func TestApplication_parseConcurrency(t *testing.T) {
+	app := newTestApp()
+	app.config.parseConcurrency = 2
+	loader := &concurrencyLoader{}
+	app.loader = loader
+
+	// the cache build parses its pages in parallel
+	if _, err := app.buildTemplateCache(); err != nil {
+		t.Fatal(err)
+	}
+	if peak := loader.peak.Load(); peak > 2 {
+		t.Errorf("%d parses ran at once building the cache, wanted at most 2", peak)
+	}
+
+	// as do concurrent renders with caching off
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = app.buildTemplateFromDisk("about.page.gohtml")
+		}()
+	}
+	wg.Wait()
+
+	if peak := loader.peak.Load(); peak > 2 {
+		t.Errorf("%d parses ran at once, wanted at most 2", peak)
+	}
+	if loader.peak.Load() < 2 {
+		t.Error("parses never overlapped; the limit wasn't exercised")
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "net/http"
+
+// previewData copies allowlisted query parameters into td.Data, so that
+// /about?title=Hello sets .Data.title. This is a development tool only: in
+// any other environment, or when no keys are configured, the query string is
+// ignored. Values are always strings, so html/template escapes them as usual.
+func (app *application) previewData(r *http.Request, td *templateData) {
+	if !app.isDevelopment() || len(app.config.previewKeys) == 0 {
+		return
+	}
+
+	query := r.URL.Query()
+	for _, key := range app.config.previewKeys {
+		if !query.Has(key) {
+			continue
+		}
+		if td.Data == nil {
+			td.Data = make(map[string]any)
+		}
+		td.Data[key] = query.Get(key)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestApplication_previewData(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		wantData bool
+	}{
+		{"development", envDevelopment, true},
+		{"production", envProduction, false},
+	}
+
+	for _, tt := range tests {
+		app := application{
+			config: appConfig{env: tt.env, previewKeys: []string{"title"}},
+		}
+
+		req, _ := http.NewRequest("GET", "/about?title=Hello&secret=nope", nil)
+		td := &templateData{}
+		app.previewData(req, td)
+
+		if _, ok := td.Data["secret"]; ok {
+			t.Errorf("%s: key not in allowlist was injected", tt.name)
+		}
+
+		got, ok := td.Data["title"]
+		if ok != tt.wantData {
+			t.Errorf("%s: title injected = %v, wanted %v", tt.name, ok, tt.wantData)
+		}
+		if tt.wantData && got != "Hello" {
+			t.Errorf("%s: wrong title; got %v, wanted Hello", tt.name, got)
+		}
+	}
+}
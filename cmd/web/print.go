@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// printKey is the request context key set by renderPrint.
+const printKey contextKey = "print"
+
+// renderPrint renders the template t for printing: with the print layout,
+// which has no navigation or footer, and with .Data.Print set so that the
+// page itself can adapt. Any page can also be printed by adding ?print=1.
+func (app *application) renderPrint(w http.ResponseWriter, r *http.Request, t string, td *templateData) (int, error) {
+	ctx := context.WithValue(r.Context(), printKey, true)
+	return app.render(w, r.WithContext(ctx), t, td)
+}
+
+// isPrint reports whether r asks for the print version of a page.
+func isPrint(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	if print, _ := r.Context().Value(printKey).(bool); print {
+		return true
+	}
+	return r.URL.Query().Get("print") == "1"
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_renderPrint(t *testing.T) {
+	app := newTestApp()
+	app.config.useCache = true
+
+	render := func(path string, print bool) string {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+
+		var err error
+		if print {
+			_, err = app.renderPrint(rr, req, "printable.page.gohtml", nil)
+		} else {
+			_, err = app.render(rr, req, "printable.page.gohtml", nil)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rr.Body.String()
+	}
+
+	normal := render("/printable", false)
+	if !strings.Contains(normal, "<nav>") || !strings.Contains(normal, `<a href="/print">Print</a>`) {
+		t.Fatalf("normal render wrong; got %s", normal)
+	}
+
+	// with the normal layout cached, the print one is still used
+	for _, print := range []string{render("/printable", true), render("/printable?print=1", false)} {
+		if strings.Contains(print, "<nav>") || strings.Contains(print, "<footer>") {
+			t.Errorf("print render has the nav or footer; got %s", print)
+		}
+		if !strings.Contains(print, `<body class="print">`) || !strings.Contains(print, "<p>Printed copy</p>") {
+			t.Errorf("print layout or .Data.Print not used; got %s", print)
+		}
+	}
+
+	// and the normal page is unaffected
+	if got := render("/printable", false); got != normal {
+		t.Errorf("normal render changed after printing; got %s", got)
+	}
+}
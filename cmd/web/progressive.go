@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+)
+
+// renderProgressive renders the page t as a sequence of sections, which
+// are templates defined by the page, and flushes each one to the client as
+// soon as it has been executed. A slow page can so have its head and above
+// the fold content on screen while the rest is still being built.
+//
+// Sections are executed one after another in the order given, all with the
+// same data, and their output is concatenated; together they must make up
+// the whole document. app.transforms are applied to each section on its
+// own, which they must allow for; app.documentTransforms, such as
+// StripComments, need the whole page and are only applied when it is
+// buffered (see below), so a flushed page keeps its comments. In
+// development the flushed page is still validated once it is complete.
+//
+// This gives up the guarantee render makes. Once the first section has
+// been flushed the status and headers are committed, so a section which
+// fails later can't become a 500: the error is logged and returned, and the
+// client is left with a truncated page. For the same reason a progressive
+// response is never compressed and has no ETag or Content-Length. A
+// failure in the first section is still reported as usual.
+//
+// When w can't flush, the sections are rendered into one buffer and sent
+// like any other page, with the usual error handling.
+func (app *application) renderProgressive(w http.ResponseWriter, r *http.Request, t string, td *templateData, sections ...string) (int, error) {
+	app.stats.renders.Add(1)
+
+	tmpl, td, err := app.prepare(r, t, td)
+	if err != nil {
+		app.renderError(w, r, t, err)
+		return 0, err
+	}
+
+	flusher, ok := flusherOf(w)
+	if !ok {
+		var page bytes.Buffer
+		for _, section := range sections {
+			html, err := app.executeSection(r, tmpl, section, td)
+			if err != nil {
+				app.renderError(w, r, t, err)
+				return 0, err
+			}
+			page.Write(html)
+		}
+
+		html, err := app.applyDocumentTransforms(page.Bytes(), r)
+		if err != nil {
+			app.renderError(w, r, t, err)
+			return 0, err
+		}
+		app.validateHTML(t, html)
+		return app.send(w, r, t, html)
+	}
+
+	w.Header().Set("Cache-Control", app.cacheControlFor(t))
+	app.pushCriticalAssets(w, t)
+
+	// the page is only kept when there is a validator to see it whole
+	var page *bytes.Buffer
+	if app.htmlValidator != nil && app.isDevelopment() {
+		page = new(bytes.Buffer)
+	}
+
+	written := 0
+	for i, section := range sections {
+		html, err := app.executeSection(r, tmpl, section, td)
+		if err != nil {
+			if i == 0 {
+				app.renderError(w, r, t, err)
+				return 0, err
+			}
+			app.stats.errors.Add(1)
+			app.log().Error("progressive render failed after flushing", "template", t, "section", section, "err", err)
+			return written, err
+		}
+		app.stats.bytesRendered.Add(int64(len(html)))
+		if page != nil {
+			page.Write(html)
+		}
+
+		n, err := w.Write(html)
+		written += n
+		app.stats.bytesSent.Add(int64(n))
+		if err != nil {
+			return written, err
+		}
+		flusher.Flush()
+	}
+
+	if page != nil {
+		app.validateHTML(t, page.Bytes())
+	}
+	return written, nil
+}
+
+// executeSection executes the section named section of a prepared page
+// template and applies the section transforms to its output.
+func (app *application) executeSection(r *http.Request, tmpl *template.Template, section string, td *templateData) ([]byte, error) {
+	buf, err := executeNamed(r.Context(), tmpl, section, td)
+	if err != nil {
+		return nil, err
+	}
+	return app.applySectionTransforms(buf.Bytes(), r)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// flushRecorder records the body written so far each time it is flushed.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes []string
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes = append(f.flushes, f.Body.String())
+	f.ResponseRecorder.Flush()
+}
+
+// noFlushWriter hides the recorder's Flush method.
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+func TestApplication_renderProgressive(t *testing.T) {
+	app := newTestApp()
+	td := &templateData{Data: map[string]any{"title": "Dashboard", "rows": []string{"a", "b"}}}
+
+	req, _ := http.NewRequest("GET", "/dashboard", nil)
+	rr := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if _, err := app.renderProgressive(rr, req, "progressive.page.gohtml", td, "top", "rest"); err != nil {
+		t.Fatal(err)
+	}
+
+	// one flush per section, each with the sections so far
+	if len(rr.flushes) != 2 {
+		t.Fatalf("expected 2 flushes, got %d", len(rr.flushes))
+	}
+	first := rr.flushes[0]
+	if !strings.Contains(first, "<h1>Dashboard</h1>") || strings.Contains(first, "<main>") {
+		t.Errorf("first flush should hold only the top; got %s", first)
+	}
+	if rr.flushes[1] != rr.Body.String() || !strings.Contains(rr.flushes[1], "<main><p>a</p><p>b</p></main>") {
+		t.Errorf("second flush should hold the whole page; got %s", rr.flushes[1])
+	}
+
+	// without flushing support the page is buffered and sent in one go
+	plain := httptest.NewRecorder()
+	if _, err := app.renderProgressive(noFlushWriter{plain}, req, "progressive.page.gohtml", td, "top", "rest"); err != nil {
+		t.Fatal(err)
+	}
+	if plain.Body.String() != rr.Body.String() {
+		t.Errorf("buffered render differs; got %s", plain.Body.String())
+	}
+	if plain.Header().Get("Content-Length") == "" || plain.Header().Get("ETag") == "" {
+		t.Error("buffered render should be sent like any other page")
+	}
+
+	// a later section failing can't change the committed status
+	rr = &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if _, err := app.renderProgressive(rr, req, "progressive.page.gohtml", td, "top", "broken"); err == nil {
+		t.Fatal("expected an error from the broken section")
+	}
+	if rr.Code != http.StatusOK || len(rr.flushes) != 1 {
+		t.Errorf("expected a 200 with the top flushed; got %d after %d flushes", rr.Code, len(rr.flushes))
+	}
+
+	// but buffered, it is a clean 500
+	plain = httptest.NewRecorder()
+	if _, err := app.renderProgressive(noFlushWriter{plain}, req, "progressive.page.gohtml", td, "top", "broken"); err == nil {
+		t.Fatal("expected an error from the broken section")
+	}
+	if plain.Code != http.StatusInternalServerError || strings.Contains(plain.Body.String(), "<h1>") {
+		t.Errorf("expected a clean 500; got %d %s", plain.Code, plain.Body.String())
+	}
+}
+
+func TestApplication_renderProgressiveTransforms(t *testing.T) {
+	app := newTestApp()
+	app.config.env = envDevelopment
+	app.transforms = []Transform{InjectBefore("</main>", "<p>section</p>")}
+	app.documentTransforms = []Transform{InjectBefore("</html>", "<!-- document -->")}
+
+	var validated []string
+	app.htmlValidator = func(t string, html []byte) []string {
+		validated = append(validated, string(html))
+		return nil
+	}
+
+	td := &templateData{Data: map[string]any{"title": "Dashboard"}}
+	req, _ := http.NewRequest("GET", "/dashboard", nil)
+
+	// flushed, only the section transforms run, and the whole page is
+	// validated once
+	rr := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if _, err := app.renderProgressive(rr, req, "progressive.page.gohtml", td, "top", "rest"); err != nil {
+		t.Fatal(err)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "<p>section</p>") || strings.Contains(body, "<!-- document -->") {
+		t.Errorf("flushed page should have only the section transform applied; got %s", body)
+	}
+	if len(validated) != 1 || validated[0] != rr.Body.String() {
+		t.Errorf("expected the flushed page validated whole once; got %q", validated)
+	}
+
+	// buffered, the document transforms run over the whole page too
+	validated = nil
+	plain := httptest.NewRecorder()
+	if _, err := app.renderProgressive(noFlushWriter{plain}, req, "progressive.page.gohtml", td, "top", "rest"); err != nil {
+		t.Fatal(err)
+	}
+	if body := plain.Body.String(); !strings.Contains(body, "<p>section</p>") || !strings.Contains(body, "<!-- document -->") {
+		t.Errorf("buffered page should have all transforms applied; got %s", body)
+	}
+	if len(validated) != 1 || validated[0] != plain.Body.String() {
+		t.Errorf("expected the buffered page validated once; got %q", validated)
+	}
+}
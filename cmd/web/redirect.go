@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// flashCookieName is the cookie carrying a flash message across a redirect.
+const flashCookieName = "flash"
+
+// flashKey is the request context key holding the flash message read by
+// Flash.
+const flashKey contextKey = "flash"
+
+// redirect sends the client to target with status, which must be a 3xx
+// code and otherwise becomes 303 See Other. Only local paths are accepted,
+// plus absolute http(s) URLs on the hosts in app.config.redirectHosts, so
+// a target taken from the request (a ?next= parameter, say) can't send the
+// visitor off to another site. Anything else is refused with a 400.
+func (app *application) redirect(w http.ResponseWriter, r *http.Request, target string, status int) {
+	if !app.safeRedirect(target) {
+		app.log().Warn("refusing redirect", "target", target)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if status < 300 || status > 399 {
+		status = http.StatusSeeOther
+	}
+
+	http.Redirect(w, r, target, status)
+}
+
+// redirectWithFlash is redirect, also leaving message for the next page to
+// show as .Data.Flash. Nothing is set if the redirect is refused.
+func (app *application) redirectWithFlash(w http.ResponseWriter, r *http.Request, target string, status int, message string) {
+	if app.safeRedirect(target) {
+		app.flash(w, message)
+	}
+	app.redirect(w, r, target, status)
+}
+
+// safeRedirect reports whether target is a local path, or an http(s) URL on
+// an allowed host.
+func (app *application) safeRedirect(target string) bool {
+	// browsers treat a backslash like a slash, so /\evil.com is //evil.com
+	if target == "" || strings.ContainsAny(target, "\\\r\n\t") {
+		return false
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme == "" && u.Host == "" && u.User == nil {
+		return strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//")
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") &&
+		u.User == nil &&
+		slices.Contains(app.config.redirectHosts, u.Hostname())
+}
+
+// flash stores message in a short-lived cookie, for the Flash middleware to
+// pick up on the next request.
+func (app *application) flash(w http.ResponseWriter, message string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString([]byte(message)),
+		Path:     "/",
+		MaxAge:   60,
+		HttpOnly: true,
+		Secure:   !app.isDevelopment(),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Flash is middleware which moves a flash message left by flash into the
+// request context, and deletes the cookie so the message is shown once.
+func (app *application) Flash(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(flashCookieName)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     flashCookieName,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+
+		message, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), flashKey, string(message))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// flashMessage returns the flash message set by Flash, if any.
+func flashMessage(r *http.Request) string {
+	message, _ := r.Context().Value(flashKey).(string)
+	return message
+}
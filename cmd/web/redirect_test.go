@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApplication_redirect(t *testing.T) {
+	app := newTestApp()
+	app.config.redirectHosts = []string{"shop.example.com"}
+
+	tests := []struct {
+		name         string
+		target       string
+		status       int
+		wantStatus   int
+		wantLocation string
+	}{
+		{"local path", "/dog-breeds?page=2", http.StatusSeeOther, http.StatusSeeOther, "/dog-breeds?page=2"},
+		{"non redirect status", "/", http.StatusOK, http.StatusSeeOther, "/"},
+		{"allowed host", "https://shop.example.com/cart", http.StatusFound, http.StatusFound, "https://shop.example.com/cart"},
+		{"external host", "https://evil.example.com/", http.StatusFound, http.StatusBadRequest, ""},
+		{"protocol relative", "//evil.example.com/", http.StatusFound, http.StatusBadRequest, ""},
+		{"backslash", `/\evil.example.com`, http.StatusFound, http.StatusBadRequest, ""},
+		{"javascript", "javascript:alert(1)", http.StatusFound, http.StatusBadRequest, ""},
+		{"relative", "dog-breeds", http.StatusFound, http.StatusBadRequest, ""},
+		{"userinfo", "https://shop.example.com@evil.example.com/", http.StatusFound, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/account", nil)
+		rr := httptest.NewRecorder()
+		app.redirect(rr, req, tt.target, tt.status)
+
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s: wrong status; got %d, wanted %d", tt.name, rr.Code, tt.wantStatus)
+		}
+		if got := rr.Header().Get("Location"); got != tt.wantLocation {
+			t.Errorf("%s: wrong Location; got %q, wanted %q", tt.name, got, tt.wantLocation)
+		}
+	}
+}
+
+func TestApplication_redirectWithFlash(t *testing.T) {
+	app := newTestApp()
+
+	req, _ := http.NewRequest("POST", "/account", nil)
+	rr := httptest.NewRecorder()
+	app.redirectWithFlash(rr, req, "/flash", http.StatusSeeOther, "Saved <your> changes")
+
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != flashCookieName {
+		t.Fatalf("no flash cookie set; got %v", cookies)
+	}
+
+	// the next page shows the message once, escaped
+	if err := app.SetTemplate("flash.page.gohtml", `<p>{{.Data.Flash}}</p>`); err != nil {
+		t.Fatal(err)
+	}
+
+	next, _ := http.NewRequest("GET", "/flash", nil)
+	next.AddCookie(cookies[0])
+	rr = httptest.NewRecorder()
+	app.Flash(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = app.render(w, r, "flash.page.gohtml", nil)
+	})).ServeHTTP(rr, next)
+
+	if got := rr.Body.String(); got != "<p>Saved &lt;your&gt; changes</p>" {
+		t.Errorf("flash not shown; got %q", got)
+	}
+	if cleared := rr.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("flash cookie not deleted; got %v", cleared)
+	}
+
+	// refused redirects leave no flash behind
+	rr = httptest.NewRecorder()
+	app.redirectWithFlash(rr, req, "https://evil.example.com/", http.StatusSeeOther, "Saved")
+	if len(rr.Result().Cookies()) != 0 {
+		t.Error("flash set for a refused redirect")
+	}
+}
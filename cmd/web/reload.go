@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// buildTemplateCache parses every page in the template directory and, only
+// if all of them parse, replaces the application's cached templates with
+// the result. On failure the cache is left as it was, so a bad edit on disk
+// can't take down pages which were working. It returns the number of
+// templates cached.
+func (app *application) buildTemplateCache() (int, error) {
+	pages, err := filepath.Glob(filepath.Join(app.templateDir(), "*.page.gohtml"))
+	if err != nil {
+		return 0, err
+	}
+
+	// Pages are parsed side by side, as many at a time as parseSlots
+	// allows.
+	templates := make([]*template.Template, len(pages))
+	errs := make([]error, len(pages))
+
+	var wg sync.WaitGroup
+	for i, page := range pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			templates[i], errs[i] = app.parseTemplateWithRetry(filepath.Base(page), baseLayout)
+		}()
+	}
+	wg.Wait()
+
+	built := make(map[string]*template.Template, len(pages))
+	for i, page := range pages {
+		if errs[i] != nil {
+			return 0, fmt.Errorf("building template cache: %w", errs[i])
+		}
+		built[filepath.Base(page)] = templates[i]
+	}
+
+	if err := app.checkTemplateBudget(built); err != nil {
+		return 0, fmt.Errorf("building template cache: %w", err)
+	}
+
+	// Requests arriving between the clear and the sets below simply
+	// build their template from disk, as on any other miss.
+	app.clearCache()
+	for t, tmpl := range built {
+		app.cacheTemplate(t, tmpl)
+	}
+
+	return len(built), nil
+}
+
+// reloadTemplates rebuilds the template cache and logs the outcome.
+func (app *application) reloadTemplates() error {
+	start := time.Now()
+
+	n, err := app.buildTemplateCache()
+	if err != nil {
+		app.log().Error("reloading templates, keeping the previous ones", "err", err)
+		return err
+	}
+
+	app.log().Info("reloaded templates", "count", n, "duration", time.Since(start))
+	return nil
+}
+
+// ReloadOnSIGHUP rebuilds the template cache whenever the process receives
+// SIGHUP, until Shutdown. It has to be called explicitly, as taking over a
+// signal is up to the program rather than this package. Requests in flight
+// carry on with the templates they already have.
+func (app *application) ReloadOnSIGHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	app.background.Add(1)
+	go func() {
+		defer app.background.Done()
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-hup:
+				_ = app.reloadTemplates()
+			case <-app.quit:
+				return
+			}
+		}
+	}()
+}
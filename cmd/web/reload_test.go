@@ -0,0 +1,66 @@
+package main
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplication_reloadTemplates(t *testing.T) {
+	app := newTestApp()
+
+	// a stale entry, which the rebuild should replace
+	stale := template.Must(template.New("about.page.gohtml").Parse("stale"))
+	app.cacheTemplate("about.page.gohtml", stale)
+
+	if err := app.reloadTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, ok := app.cachedTemplate("about.page.gohtml")
+	if !ok {
+		t.Fatal("about.page.gohtml not cached after reload")
+	}
+	if tmpl == stale {
+		t.Error("stale template still cached after reload")
+	}
+
+	if _, ok := app.cachedTemplate("about.draft.gohtml"); ok {
+		t.Error("draft cached by reload")
+	}
+}
+
+func TestApplication_reloadTemplatesKeepsCacheOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "partials"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"base.layout.gohtml":             `{{define "base"}}{{block "content" .}}{{end}}{{end}}`,
+		"partials/header.partial.gohtml": `{{define "header"}}{{end}}`,
+		"partials/footer.partial.gohtml": `{{define "footer"}}{{end}}`,
+		"good.page.gohtml":               `{{template "base" .}}`,
+		"broken.page.gohtml":             `{{template "base" .}`,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := newTestApp()
+	app.config.templateDir = dir
+
+	previous := template.Must(template.New("good.page.gohtml").Parse("previous"))
+	app.cacheTemplate("good.page.gohtml", previous)
+
+	if err := app.reloadTemplates(); err == nil {
+		t.Fatal("expected an error rebuilding with a broken template")
+	}
+
+	if tmpl, ok := app.cachedTemplate("good.page.gohtml"); !ok || tmpl != previous {
+		t.Error("previous cache not kept after a failed reload")
+	}
+}
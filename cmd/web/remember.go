@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	rememberCookieName = "remember_me"
+	rememberTTL        = 30 * 24 * time.Hour
+)
+
+type contextKey string
+
+// rememberedUserKey is the request context key holding the user id from a
+// valid remember-me cookie.
+const rememberedUserKey contextKey = "rememberedUser"
+
+var (
+	errInvalidToken = errors.New("invalid remember-me token")
+	errExpiredToken = errors.New("expired remember-me token")
+)
+
+// newRememberToken returns a token for userID which expires at expires. The
+// token is the base64 encoded payload "userID|expiry", a dot, and the base64
+// encoded HMAC-SHA256 of the payload.
+func (app *application) newRememberToken(userID string, expires time.Time) string {
+	payload := userID + "|" + strconv.FormatInt(expires.Unix(), 10)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(app.signRememberToken([]byte(payload)))
+}
+
+// verifyRememberToken checks the signature and expiry of token, returning
+// the user id it carries.
+func (app *application) verifyRememberToken(token string) (string, error) {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", errInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return "", errInvalidToken
+	}
+
+	// hmac.Equal compares in constant time
+	if !hmac.Equal(sig, app.signRememberToken(payload)) {
+		return "", errInvalidToken
+	}
+
+	userID, expiry, ok := strings.Cut(string(payload), "|")
+	if !ok || userID == "" {
+		return "", errInvalidToken
+	}
+
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", errInvalidToken
+	}
+
+	if time.Now().After(time.Unix(unix, 0)) {
+		return "", errExpiredToken
+	}
+
+	return userID, nil
+}
+
+func (app *application) signRememberToken(payload []byte) []byte {
+	mac := hmac.New(sha256.New, app.config.rememberSecret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// remember issues a remember-me cookie for userID.
+func (app *application) remember(w http.ResponseWriter, userID string) {
+	expires := time.Now().Add(rememberTTL)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     rememberCookieName,
+		Value:    app.newRememberToken(userID, expires),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   !app.isDevelopment(),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// forget clears the remember-me cookie.
+func (app *application) forget(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     rememberCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// RememberMe is middleware which verifies the remember-me cookie. A valid
+// token puts its user id in the request context, where rememberedUser finds
+// it; an expired or tampered one is rejected and the cookie cleared. It does
+// nothing unless a secret has been configured.
+func (app *application) RememberMe(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(rememberCookieName)
+		if len(app.config.rememberSecret) == 0 || err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, err := app.verifyRememberToken(cookie.Value)
+		if err != nil {
+			app.forget(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), rememberedUserKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// rememberedUser returns the user id set by RememberMe, if any.
+func rememberedUser(r *http.Request) (string, bool) {
+	userID, ok := r.Context().Value(rememberedUserKey).(string)
+	return userID, ok
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestApplication_RememberMe(t *testing.T) {
+	app := application{config: appConfig{rememberSecret: []byte("secret")}}
+
+	valid := app.newRememberToken("42", time.Now().Add(time.Hour))
+	expired := app.newRememberToken("42", time.Now().Add(-time.Hour))
+
+	other := application{config: appConfig{rememberSecret: []byte("other secret")}}
+	tampered := other.newRememberToken("42", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name        string
+		token       string
+		wantUser    bool
+		wantCleared bool
+	}{
+		{"valid", valid, true, false},
+		{"expired", expired, false, true},
+		{"tampered", tampered, false, true},
+		{"garbage", "not-a-token", false, true},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: rememberCookieName, Value: tt.token})
+		rr := httptest.NewRecorder()
+
+		var authenticated bool
+		handler := app.RememberMe(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authenticated = app.defaultData(r)["IsAuthenticated"].(bool)
+		}))
+		handler.ServeHTTP(rr, req)
+
+		if authenticated != tt.wantUser {
+			t.Errorf("%s: IsAuthenticated = %v, wanted %v", tt.name, authenticated, tt.wantUser)
+		}
+
+		cleared := false
+		for _, c := range rr.Result().Cookies() {
+			if c.Name == rememberCookieName && c.MaxAge < 0 {
+				cleared = true
+			}
+		}
+		if cleared != tt.wantCleared {
+			t.Errorf("%s: cookie cleared = %v, wanted %v", tt.name, cleared, tt.wantCleared)
+		}
+	}
+}
@@ -1,12 +1,27 @@
 package main
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"errors"
 	"html/template"
-	"log"
+	"io"
 	"net/http"
+	"path/filepath"
+	"strconv"
 )
 
+// Layouts a page can be rendered with. Both define "base", which pages
+// invoke; the print layout leaves out the navigation and footer.
+const (
+	baseLayout  = "base.layout.gohtml"
+	printLayout = "print.layout.gohtml"
+)
+
+// defaultTemplateDir is where templates are read from when no directory
+// has been configured.
+const defaultTemplateDir = "./templates"
+
 // templateData holds dynamic data that will be passed to templates.
 // The map allows storing any kind of value (string, int, struct, etc.)
 // which makes templates flexible.
@@ -14,81 +29,504 @@ type templateData struct {
 	Data map[string]any
 }
 
+// MergeFrom copies the entries of other into td.Data. Keys which are already
+// set are only replaced when overwrite is true. It is a no-op on a nil
+// templateData, and a nil Data map is created when needed.
+func (td *templateData) MergeFrom(other map[string]any, overwrite bool) {
+	if td == nil || len(other) == 0 {
+		return
+	}
+
+	if td.Data == nil {
+		td.Data = make(map[string]any, len(other))
+	}
+
+	for key, value := range other {
+		if _, exists := td.Data[key]; exists && !overwrite {
+			continue
+		}
+		td.Data[key] = value
+	}
+}
+
+// DataProvider contributes template data for a request, for values that
+// many pages need but which are not worth computing in every handler.
+type DataProvider func(r *http.Request) map[string]any
+
 // render is responsible for:
 // 1. Finding the requested template
 // 2. Loading it from cache or disk
 // 3. Executing it and sending HTML to the browser
-func (app *application) render(w http.ResponseWriter, t string, td *templateData) {
+//
+// The page is executed into a buffer before anything is written, so a
+// failing template produces a clean 500 rather than half a page. Like
+// io.Writer, render returns the number of bytes written to w, which is the
+// compressed size when the response is gzipped. The uncompressed size isn't
+// returned: it is only added to app.stats.bytesRendered, a total over all
+// renders. opts adjust this render only.
+func (app *application) render(w http.ResponseWriter, r *http.Request, t string, td *templateData, opts ...RenderOption) (int, error) {
+	app.stats.renders.Add(1)
+
+	buf, err := app.execute(r, t, td, opts...)
+	if err != nil {
+		app.renderError(w, r, t, err)
+		return 0, err
+	}
+
+	return app.send(w, r, t, buf.Bytes())
+}
+
+// renderError answers a request whose page t failed to render with err.
+func (app *application) renderError(w http.ResponseWriter, r *http.Request, t string, err error) {
+	// Drafts and pages switched off by a feature gate don't exist as far
+	// as the client is concerned.
+	if errors.Is(err, errNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	// The client has gone away, so there is no one to write to.
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		app.stats.errors.Add(1)
+		app.log().Error("timed out rendering template", "template", t)
+		http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+		return
+	}
+
+	app.stats.errors.Add(1)
+
+	// The details, which name templates and their internals, are only
+	// logged.
+	var pe *ParseError
+	var ee *ExecError
+	switch {
+	case errors.As(err, &pe):
+		app.log().Error("parsing template", "template", pe.Template, "err", pe.Err)
+	case errors.As(err, &ee):
+		app.log().Error("executing template", "template", t, "name", ee.Template, "err", ee.Err)
+	default:
+		app.log().Error("rendering template", "template", t, "err", err)
+	}
+
+	// Send a 500 Internal Server Error response to the client
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// send writes the fully rendered page t to w, setting its caching headers
+// and compressing it if the client accepts that.
+func (app *application) send(w http.ResponseWriter, r *http.Request, t string, body []byte) (int, error) {
+	// Let browsers cache the page only if its template is marked as cacheable.
+	w.Header().Set("Cache-Control", app.cacheControlFor(t))
+
+	app.stats.bytesRendered.Add(int64(len(body)))
+
+	// Compress the body if gzip is enabled and the client accepts it.
+	// Whether or not it is compressed, the response then depends on
+	// Accept-Encoding, so caches must keep the two apart.
+	compress := app.config.gzip && acceptsGzip(r)
+	if app.config.gzip {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	// Tag the page with a hash of its content, and skip sending it again
+	// to a client which already has it. Error pages aren't tagged, as a
+	// 304 would stand in for their status.
+	if responseStatus(w) == http.StatusOK {
+		etag := contentETag(body, compress)
+		w.Header().Set("ETag", etag)
+		if notModified(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return 0, nil
+		}
+	}
+
+	if compress {
+		var err error
+		if body, err = gzipBytes(body); err != nil {
+			app.stats.errors.Add(1)
+			app.log().Error("compressing response", "template", t, "err", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return 0, err
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+	}
+
+	// The whole body is known, so send its length rather than have it
+	// chunked. body is final by now: transforms ran in execute, and
+	// compression has been applied above.
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+
+	// Over HTTP/2, push the page's critical assets before the body.
+	app.pushCriticalAssets(w, t)
+
+	n, err := w.Write(body)
+	app.stats.bytesSent.Add(int64(n))
+
+	return n, err
+}
+
+// renderTo renders the template t into w, which need not be an HTTP
+// response, and returns the number of bytes written. No headers are set and
+// the output is never compressed.
+func (app *application) renderTo(w io.Writer, r *http.Request, t string, td *templateData, opts ...RenderOption) (int, error) {
+	buf, err := app.execute(r, t, td, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(buf.Bytes())
+	return n, err
+}
+
+// execute finds the template t, builds its data and executes it into a
+// buffer.
+func (app *application) execute(r *http.Request, t string, td *templateData, opts ...RenderOption) (*bytes.Buffer, error) {
+	tmpl, td, err := app.prepare(r, t, td, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := executeNamed(r.Context(), tmpl, t, td)
+	if err != nil {
+		return nil, err
+	}
+
+	// Post-process the output, for instance to rewrite asset paths.
+	html, err := app.applyTransforms(buf.Bytes(), r)
+	if err != nil {
+		return nil, err
+	}
+
+	// In development, report markup the validator, if any, objects to.
+	app.validateHTML(t, html)
+
+	return bytes.NewBuffer(html), nil
+}
+
+// executeNamed executes the template called name in tmpl with td and
+// returns its output:
+// - `buf` collects the output, which the caller writes out
+// - `name` is the template name to execute
+// - `td` is the dynamic data passed to the template
+// Execution runs in its own goroutine so that we can stop waiting for it
+// when ctx is done. The goroutine can't be interrupted while it is in a
+// template function, but it stops at its next write, as the writer it
+// executes into fails once ctx is done; its output is simply dropped.
+func executeNamed(ctx context.Context, tmpl namedExecutor, name string, td *templateData) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- tmpl.ExecuteTemplate(ctxWriter{ctx: ctx, w: &buf}, name, td)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, &ExecError{Template: name, Err: err}
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return &buf, nil
+}
+
+// namedExecutor is a template set which can execute one of its templates
+// by name, as both html/template and text/template templates can.
+type namedExecutor interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+// ctxWriter is an io.Writer which fails with ctx's error once ctx is done.
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw ctxWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
+
+// prepare finds the template t, with the request's functions bound, and
+// builds the data to execute it with.
+func (app *application) prepare(r *http.Request, t string, td *templateData, opts ...RenderOption) (*template.Template, *templateData, error) {
+	var o renderOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Drafts are only shown to editors. This is checked before the
+	// template is looked up, so the cache can't serve one to anyone else.
+	if !app.canRender(r, t) {
+		return nil, nil, errDraftNotFound
+	}
+
+	// Pages behind a feature flag that is off aren't even parsed.
+	if app.features != nil && !app.features.Allow(t) {
+		return nil, nil, errFeatureDisabled
+	}
+
+	// Print views get the stripped down print layout.
+	layout := baseLayout
+	if isPrint(r) {
+		layout = printLayout
+	}
+
+	tmpl, err := app.getLayoutTemplate(t, layout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Work on a copy of the template with the request's functions bound.
+	tmpl, err = app.bindRequest(tmpl, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Functions passed with WithFuncs override the others on the copy,
+	// so nothing else sees them.
+	if len(o.funcs) > 0 {
+		tmpl = tmpl.Funcs(o.funcs)
+	}
+
+	// If no template data was provided,
+	// initialize an empty templateData struct
+	// to avoid nil pointer errors in templates.
+	if td == nil {
+		td = &templateData{}
+	}
+
+	// Combine the handler's data with the defaults and providers.
+	td = app.composeData(r, td)
+
+	// Data given as functions is only computed if the template uses it.
+	wrapLazy(td)
+
+	// Inline the page's critical CSS, if it has any, for the layout's head.
+	if css := app.criticalCSS(t); css != "" {
+		td.MergeFrom(map[string]any{"CriticalCSS": css}, false)
+	}
+
+	// In development, allow allowlisted query parameters to override
+	// template data so pages can be previewed with arbitrary values.
+	app.previewData(r, td)
+
+	// In development, check the data against the template's schema, if it
+	// has one, so that a missing key is reported clearly.
+	if err := app.validateData(t, td); err != nil {
+		return nil, nil, err
+	}
+
+	// Also in development, warn about suspiciously large data.
+	app.warnLargeData(t, td)
+
+	return tmpl, td, nil
+}
+
+// composeData builds the data for a render. Sources are applied in order,
+// each overwriting keys set by the ones before:
+// 1. defaultData, for values every page gets
+// 2. app.providers, in the order they were added
+// 3. providers added with ProvideFor whose prefix matches the path
+// 4. the data passed in by the handler
+// so the handler always wins.
+func (app *application) composeData(r *http.Request, td *templateData) *templateData {
+	composed := &templateData{}
+	composed.MergeFrom(app.defaultData(r), true)
+
+	for _, provide := range app.providers {
+		composed.MergeFrom(provide(r), true)
+	}
+
+	for _, p := range app.scopedProviders {
+		if p.matches(r) {
+			composed.MergeFrom(p.provide(r), true)
+		}
+	}
+
+	composed.MergeFrom(td.Data, true)
+
+	return composed
+}
+
+// defaultData returns the values made available to every template.
+func (app *application) defaultData(r *http.Request) map[string]any {
+	_, authenticated := rememberedUser(r)
+
+	return map[string]any{
+		"IsAuthenticated": authenticated,
+		"Breadcrumbs":     app.breadcrumbs(r.URL.EscapedPath()),
+		"Theme":           app.theme(r),
+		"Variant":         experimentVariants(r),
+		"Device":          app.device(r),
+		"Flash":           flashMessage(r),
+		"Version":         app.buildInfo(),
+		"Print":           isPrint(r),
+	}
+}
+
+// getTemplate returns the compiled template t, loading it from the cache
+// or from disk.
+func (app *application) getTemplate(t string) (*template.Template, error) {
+	return app.getLayoutTemplate(t, baseLayout)
+}
+
+// getLayoutTemplate returns the compiled template t laid out with layout.
+// Each layout of a page is cached separately.
+func (app *application) getLayoutTemplate(t, layout string) (*template.Template, error) {
 	var tmpl *template.Template
 
+	// Templates registered in memory with SetTemplate take precedence
+	// over everything else, whether or not caching is enabled.
+	app.cacheMu.RLock()
+	overlay, ok := app.overlays[t]
+	app.cacheMu.RUnlock()
+	if ok {
+		return overlay, nil
+	}
+
 	// If template caching is enabled, try to fetch the template
-	// from the in-memory map instead of reading from disk.
+	// from the in-memory cache instead of reading from disk.
 	// This improves performance in production.
+	reason := missCachingDisabled
 	if app.config.useCache {
-		// Check if the template exists in the map
-		if templateFromMap, ok := app.templateMap[t]; ok {
-			tmpl = templateFromMap
+		// Check if the template exists in the cache
+		if templateFromCache, ok := app.cachedTemplate(layoutVariant(t, layout)); ok {
+			tmpl = templateFromCache
 		}
+		reason = missAbsent
 	}
 
 	// If tmpl is still nil, it means:
 	// - caching is disabled, OR
 	// - template was not found in cache
 	// So we build (parse) the template from disk.
+	// The cache's lock has been released by now, so the hooks are free
+	// to do anything, including render.
 	if tmpl == nil {
-		newTemplate, err := app.buildTemplateFromDisk(t)
+		app.stats.cacheMisses.Add(1)
+		app.logCacheMiss(t, reason)
+		if app.OnCacheMiss != nil {
+			app.OnCacheMiss(t)
+		}
+		newTemplate, err := app.buildLayoutTemplate(t, layout)
 		if err != nil {
-			log.Println("Error building template:", err)
-			return
+			return nil, err
 		}
-		log.Println("building template from disk")
 		tmpl = newTemplate
+	} else {
+		app.stats.cacheHits.Add(1)
+		if app.OnCacheHit != nil {
+			app.OnCacheHit(t)
+		}
 	}
 
-	// If no template data was provided,
-	// initialize an empty templateData struct
-	// to avoid nil pointer errors in templates.
-	if td == nil {
-		td = &templateData{}
-	}
-
-	// Execute the template:
-	// - `w` is the HTTP response writer
-	// - `t` is the template name to execute
-	// - `td` is the dynamic data passed to the template
-	if err := tmpl.ExecuteTemplate(w, t, td); err != nil {
-		log.Println("Error executing template:", err)
-
-		// Send a 500 Internal Server Error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+	return tmpl, nil
 }
 
 // buildTemplateFromDisk parses templates from files and returns a compiled template.
 // This is usually used when caching is disabled or template is not found in cache.
 func (app *application) buildTemplateFromDisk(t string) (*template.Template, error) {
+	return app.buildLayoutTemplate(t, baseLayout)
+}
+
+// buildLayoutTemplate is buildTemplateFromDisk with the given layout.
+func (app *application) buildLayoutTemplate(t, layout string) (*template.Template, error) {
+	// Parse all template files into a single template object,
+	// retrying if the filesystem has a hiccup
+	tmpl, err := app.parseTemplateWithRetry(t, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the compiled template in the cache
+	// so it can be reused later without re-parsing
+	app.cacheTemplate(layoutVariant(t, layout), tmpl)
 
+	return tmpl, nil
+}
+
+// layoutVariant names the template t laid out with layout in the cache:
+// just t for the base layout, so those keys are unchanged.
+func layoutVariant(t, layout string) string {
+	if layout == baseLayout {
+		return t
+	}
+	return t + "@" + layout
+}
+
+// parseTemplate parses the page t from disk together with layout and the
+// partials, without touching the cache.
+func (app *application) parseTemplate(t, layout string) (*template.Template, error) {
 	// List of templates to be parsed together.
 	// Order matters:
-	// - base layout first
-	// - shared partials (header/footer)
+	// - layout first
+	// - shared partials (header/footer, then any others)
 	// - page-specific template last
+	dir := app.templateDir()
+
 	templateSlice := []string{
-		"./templates/base.layout.gohtml",
-		"./templates/partials/header.partial.gohtml",
-		"./templates/partials/footer.partial.gohtml",
-		fmt.Sprintf("./templates/%s", t),
+		filepath.Join(dir, layout),
+		filepath.Join(dir, "partials", "header.partial.gohtml"),
+		filepath.Join(dir, "partials", "footer.partial.gohtml"),
 	}
+	templateSlice = append(templateSlice, extraPartials(dir)...)
+	templateSlice = append(templateSlice, filepath.Join(dir, t))
 
-	// Parse all template files into a single template object
-	tmpl, err := template.ParseFiles(templateSlice...)
-	if err != nil {
-		return nil, err
+	return app.parseTemplateFiles(app.newTemplate(t), templateSlice...)
+}
+
+// extraPartials lists the partials in dir other than the header and footer:
+// those used with the partial template function, and files of shared
+// {{define}} blocks, which every page can then use with {{template}}
+// without listing the file. The directory is listed from disk, whichever
+// loader reads the files.
+func extraPartials(dir string) []string {
+	matches, _ := filepath.Glob(filepath.Join(dir, "partials", "*.partial.gohtml"))
+
+	var extra []string
+	for _, match := range matches {
+		switch filepath.Base(match) {
+		case "header.partial.gohtml", "footer.partial.gohtml":
+		default:
+			extra = append(extra, match)
+		}
 	}
+	return extra
+}
 
-	// Store the compiled template in the map
-	// so it can be reused later without re-parsing
-	app.templateMap[t] = tmpl
+// newTemplate returns an empty template named t, using the configured
+// delimiters (empty means the standard {{ and }}) and with the template
+// functions bound, ready for parsing.
+func (app *application) newTemplate(t string) *template.Template {
+	tmpl := template.New(t).Delims(app.config.leftDelim, app.config.rightDelim)
+	return tmpl.Funcs(app.templateFuncs())
+}
 
-	return tmpl, nil
+// templateFuncs returns the functions available to templates, which must
+// be bound before parsing. Functions that need the request are only
+// placeholders here, and are replaced by bindRequest on every render.
+func (app *application) templateFuncs() template.FuncMap {
+	funcs := requestFuncPlaceholders()
+	funcs["jsonld"] = jsonLD
+	funcs["lazy"] = lazy
+	for name, fn := range app.funcs {
+		funcs[name] = fn
+	}
+
+	return funcs
+}
+
+// templateDir returns the configured template directory, or the default
+// when none has been set.
+func (app *application) templateDir() string {
+	if app.config.templateDir == "" {
+		return defaultTemplateDir
+	}
+	return app.config.templateDir
 }
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTemplateData_MergeFrom(t *testing.T) {
+	other := map[string]any{"title": "new", "extra": 1}
+
+	td := &templateData{Data: map[string]any{"title": "old"}}
+	td.MergeFrom(other, false)
+
+	if td.Data["title"] != "old" {
+		t.Errorf("existing key replaced without overwrite; got %v", td.Data["title"])
+	}
+	if td.Data["extra"] != 1 {
+		t.Error("new key not merged without overwrite")
+	}
+
+	td = &templateData{Data: map[string]any{"title": "old"}}
+	td.MergeFrom(other, true)
+
+	if td.Data["title"] != "new" {
+		t.Errorf("existing key not replaced with overwrite; got %v", td.Data["title"])
+	}
+
+	// nil receivers and maps must be safe
+	var nilData *templateData
+	nilData.MergeFrom(other, true)
+
+	td = &templateData{}
+	td.MergeFrom(other, false)
+	if len(td.Data) != 2 {
+		t.Errorf("merge into nil map failed; got %v", td.Data)
+	}
+}
+
+func TestApplication_composeData(t *testing.T) {
+	app := application{
+		providers: []DataProvider{
+			func(r *http.Request) map[string]any {
+				return map[string]any{"title": "first provider", "nav": "first"}
+			},
+			func(r *http.Request) map[string]any {
+				return map[string]any{"nav": "second"}
+			},
+		},
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	td := app.composeData(req, &templateData{Data: map[string]any{"title": "handler"}})
+
+	if td.Data["title"] != "handler" {
+		t.Errorf("handler data should win; got %v", td.Data["title"])
+	}
+	if td.Data["nav"] != "second" {
+		t.Errorf("later provider should win; got %v", td.Data["nav"])
+	}
+}
+
+func TestApplication_renderCustomDelims(t *testing.T) {
+	app := newTestApp()
+	app.config.templateDir = "./testdata/delims"
+	app.config.leftDelim = "[["
+	app.config.rightDelim = "]]"
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	app.render(rr, req, "vue.page.gohtml", &templateData{Data: map[string]any{"title": "Hello"}})
+
+	body := rr.Body.String()
+	if !strings.Contains(body, "<h1>Hello</h1>") {
+		t.Errorf("custom delimiter action not executed; got %s", body)
+	}
+	if !strings.Contains(body, "<p>{{ message }}</p>") {
+		t.Errorf("standard delimiters not left for the client; got %s", body)
+	}
+}
+
+func TestApplication_renderSharedDefine(t *testing.T) {
+	app := newTestApp()
+
+	type breed struct{ Name, Origin string }
+	td := &templateData{Data: map[string]any{"Breeds": []breed{{"Beagle", "England"}, {"Akita", "Japan"}}}}
+
+	req, _ := http.NewRequest("GET", "/breeds", nil)
+	rr := httptest.NewRecorder()
+	if _, err := app.render(rr, req, "breeds.page.gohtml", td); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<main><div class="card"><h2>Beagle</h2><p>England</p></div><div class="card"><h2>Akita</h2><p>Japan</p></div></main>`
+	if !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("cards not rendered from the shared define; got %s", rr.Body.String())
+	}
+}
+
+func TestApplication_renderByteCount(t *testing.T) {
+	app := newTestApp()
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/about", nil)
+
+	n, err := app.render(rr, req, "about.page.gohtml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != rr.Body.Len() {
+		t.Errorf("wrong byte count; got %d, wanted %d", n, rr.Body.Len())
+	}
+
+	// with gzip, the count is of the compressed bytes actually sent
+	app.config.gzip = true
+	rendered := app.stats.bytesRendered.Load()
+
+	rr = httptest.NewRecorder()
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	n, err = app.render(rr, req, "about.page.gohtml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("response was not compressed")
+	}
+	if n != rr.Body.Len() {
+		t.Errorf("wrong compressed byte count; got %d, wanted %d", n, rr.Body.Len())
+	}
+
+	zr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := io.ReadAll(zr)
+
+	if got := app.stats.bytesRendered.Load() - rendered; got != int64(len(plain)) {
+		t.Errorf("wrong uncompressed byte count; got %d, wanted %d", got, len(plain))
+	}
+
+	// a client which doesn't accept gzip gets the page uncompressed, but
+	// still varying on Accept-Encoding
+	rr = httptest.NewRecorder()
+	req.Header.Del("Accept-Encoding")
+	if _, err := app.render(rr, req, "about.page.gohtml", nil); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Header().Get("Content-Encoding") != "" {
+		t.Error("response compressed for a client not accepting gzip")
+	}
+	if rr.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("uncompressed response lacks Vary; got %q", rr.Header().Get("Vary"))
+	}
+}
+
+func TestApplication_renderTo(t *testing.T) {
+	app := newTestApp()
+
+	var buf bytes.Buffer
+	req, _ := http.NewRequest("GET", "/about", nil)
+
+	n, err := app.renderTo(&buf, req, "about.page.gohtml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != buf.Len() || !strings.Contains(buf.String(), "<main>about</main>") {
+		t.Errorf("wrong output; got %d bytes: %s", n, buf.String())
+	}
+
+	if _, err := app.renderTo(&buf, req, "missing.page.gohtml", nil); err == nil {
+		t.Error("expected an error for a missing template")
+	}
+}
+
+// sleeper lets a template take a while to execute.
+type sleeper struct {
+	d time.Duration
+}
+
+func (s sleeper) Sleep() string {
+	time.Sleep(s.d)
+	return "done"
+}
+
+func TestApplication_renderContextDeadline(t *testing.T) {
+	app := newTestApp()
+	if err := app.SetTemplate("slow.page.gohtml", `{{.Data.sleeper.Sleep}}`); err != nil {
+		t.Fatal(err)
+	}
+	td := &templateData{Data: map[string]any{"sleeper": sleeper{d: 100 * time.Millisecond}}}
+
+	// the deadline passes while the template is executing
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/slow", nil)
+	rr := httptest.NewRecorder()
+
+	if _, err := app.render(rr, req, "slow.page.gohtml", td); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrong error; got %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	if rr.Code != http.StatusGatewayTimeout {
+		t.Errorf("wrong response code; got %d, wanted 504", rr.Code)
+	}
+
+	// a client that has gone away gets nothing at all
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	req, _ = http.NewRequestWithContext(ctx, "GET", "/slow", nil)
+	rr = httptest.NewRecorder()
+
+	if _, err := app.render(rr, req, "slow.page.gohtml", td); !errors.Is(err, context.Canceled) {
+		t.Errorf("wrong error; got %v, wanted %v", err, context.Canceled)
+	}
+	if rr.Body.Len() != 0 || len(rr.Header()) != 0 {
+		t.Errorf("response written for a cancelled request: %v %q", rr.Header(), rr.Body.String())
+	}
+}
+
+// countingSleeper counts the calls to Sleep, each of which takes d.
+type countingSleeper struct {
+	d     time.Duration
+	calls *atomic.Int64
+}
+
+func (s countingSleeper) Sleep() string {
+	time.Sleep(s.d)
+	s.calls.Add(1)
+	return "done"
+}
+
+func TestApplication_renderStopsAfterDeadline(t *testing.T) {
+	app := newTestApp()
+	if err := app.SetTemplate("slow.page.gohtml", `{{range .Data.items}}{{$.Data.sleeper.Sleep}}{{end}}`); err != nil {
+		t.Fatal(err)
+	}
+	calls := &atomic.Int64{}
+	td := &templateData{Data: map[string]any{
+		"items":   make([]int, 50),
+		"sleeper": countingSleeper{d: 2 * time.Millisecond, calls: calls},
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "/slow", nil)
+	if _, err := app.render(httptest.NewRecorder(), req, "slow.page.gohtml", td); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wrong error; got %v, wanted %v", err, context.DeadlineExceeded)
+	}
+
+	// execution stops at the first write after the deadline, rather than
+	// running on to the end of the page
+	time.Sleep(20 * time.Millisecond)
+	stopped := calls.Load()
+	time.Sleep(20 * time.Millisecond)
+
+	if got := calls.Load(); got != stopped || got == 50 {
+		t.Errorf("template kept executing after the deadline; %d calls, then %d", stopped, got)
+	}
+}
+
+func TestApplication_renderContentLength(t *testing.T) {
+	app := newTestApp()
+
+	render := func(acceptGzip bool) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/about", nil)
+		if acceptGzip {
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+		rr := httptest.NewRecorder()
+		if _, err := app.render(rr, req, "about.page.gohtml", nil); err != nil {
+			t.Fatal(err)
+		}
+		return rr
+	}
+
+	check := func(name string, rr *httptest.ResponseRecorder) {
+		if got, want := rr.Header().Get("Content-Length"), strconv.Itoa(rr.Body.Len()); got != want {
+			t.Errorf("%s: wrong Content-Length; got %s, wanted %s", name, got, want)
+		}
+	}
+
+	check("plain", render(false))
+
+	// a transform changing the size is accounted for
+	app.transforms = []Transform{InjectBefore("</body>", "<script>analytics()</script>")}
+	check("transformed", render(false))
+
+	// as is compression
+	app.config.gzip = true
+	check("gzipped", render(true))
+}
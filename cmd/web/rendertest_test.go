@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RenderTestRequest renders the page at path with td through the whole
+// pipeline, as a request for method and path would, and returns what was
+// written. The request passes through the same middleware as in routes, so
+// cookies, default data and providers all behave as they do in production.
+// The template is the one registered for path, or otherwise the one
+// ShowPage would pick.
+func (app *application) RenderTestRequest(method, path string, td *templateData) *httptest.ResponseRecorder {
+	t := strings.Trim(path, "/") + ".page.gohtml"
+	for _, p := range app.pages {
+		if p.path == path {
+			t = p.template
+		}
+	}
+
+	handler := chi.Chain(app.middleware()...).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = app.render(w, r, t, td)
+	})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+
+	return rr
+}
+
+func TestApplication_RenderTestRequest(t *testing.T) {
+	app := newTestApp()
+	app.config.experiments = []experiment{{Name: "hero", Variants: []variant{{Name: "big", Weight: 1}}}}
+
+	if err := app.SetTemplate("hero.page.gohtml", `<p>{{.Data.Breed}} {{index .Data.Variant "hero"}} {{.Data.Device}}</p>`); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := app.RenderTestRequest("GET", "/hero", &templateData{Data: map[string]any{"Breed": "Beagle"}})
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("wrong status; got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != cacheControlNoStore {
+		t.Errorf("wrong Cache-Control; got %q", got)
+	}
+	if !strings.Contains(rr.Header().Get("Set-Cookie"), "exp_hero=big") {
+		t.Errorf("experiment middleware didn't run; Set-Cookie %q", rr.Header().Get("Set-Cookie"))
+	}
+	if got := rr.Body.String(); got != "<p>Beagle big desktop</p>" {
+		t.Errorf("wrong body; got %q", got)
+	}
+
+	// registered pages render their own template
+	if err := app.RegisterPage("/", "about.page.gohtml", nil); err != nil {
+		t.Fatal(err)
+	}
+	rr = app.RenderTestRequest("GET", "/", nil)
+	if !strings.Contains(rr.Body.String(), "<main>about</main>") {
+		t.Errorf("registered page not rendered; got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// bindRequest returns a copy of tmpl with the request-scoped template
+// functions bound to r, which may be nil outside of a request.
+//
+// Cached templates are never executed themselves, only these copies:
+// html/template can't clone a template once it has run, and the copy is
+// what lets each render have functions of its own.
+func (app *application) bindRequest(tmpl *template.Template, r *http.Request) (*template.Template, error) {
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	return clone.Funcs(app.requestFuncs(r, clone)), nil
+}
+
+// requestFuncs returns the template functions which depend on the current
+// request or template. Each also has a placeholder in templateFuncs, as
+// functions must be known when a template is parsed.
+func (app *application) requestFuncs(r *http.Request, tmpl *template.Template) template.FuncMap {
+	current := &url.URL{Path: "/"}
+	if r != nil {
+		current = r.URL
+	}
+	device := app.device(r)
+
+	return template.FuncMap{
+		"cachefragment": func(name string, ttl int, data any) (template.HTML, error) {
+			return app.fragments.render(tmpl, name, time.Duration(ttl)*time.Second, data)
+		},
+		"pageURL": func(page int) string {
+			return pageURL(current, page)
+		},
+		"partial": func(name string, data any) (template.HTML, error) {
+			return renderPartial(tmpl, device, name, data)
+		},
+	}
+}
+
+// requestFuncPlaceholders stand in for requestFuncs while parsing.
+func requestFuncPlaceholders() template.FuncMap {
+	errUnbound := errors.New("template function used outside of a render")
+
+	return template.FuncMap{
+		"cachefragment": func(string, int, any) (template.HTML, error) { return "", errUnbound },
+		"pageURL":       func(int) (string, error) { return "", errUnbound },
+		"partial":       func(string, any) (template.HTML, error) { return "", errUnbound },
+	}
+}
+
+// pageURL returns current with its page query parameter set to page,
+// keeping every other parameter, including repeated ones, as it is. It is
+// the pageURL template function:
+//
+//	<a href="{{pageURL 2}}">Next</a>
+func pageURL(current *url.URL, page int) string {
+	query := current.Query()
+	query.Set("page", strconv.Itoa(page))
+
+	u := url.URL{Path: current.Path, RawQuery: query.Encode()}
+	return u.String()
+}
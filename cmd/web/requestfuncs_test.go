@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_pageURL(t *testing.T) {
+	app := newTestApp()
+	app.config.useCache = true
+	if err := app.SetTemplate("list.page.gohtml", `<a href="{{pageURL 3}}">next</a>`); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "/dog-breeds?color=black&color=brown&sort=name&page=2", nil)
+
+	// render twice, so the second render runs on an already used template
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		if _, err := app.render(rr, req, "list.page.gohtml", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		want := `<a href="/dog-breeds?color=black&amp;color=brown&amp;page=3&amp;sort=name">next</a>`
+		if rr.Body.String() != want {
+			t.Errorf("render %d: wrong link; got %s, wanted %s", i, rr.Body.String(), want)
+		}
+	}
+
+	// each render gets the URL of its own request
+	req, _ = http.NewRequest("GET", "/cat-breeds", nil)
+	rr := httptest.NewRecorder()
+	_, _ = app.render(rr, req, "list.page.gohtml", nil)
+
+	if !strings.Contains(rr.Body.String(), `href="/cat-breeds?page=3"`) {
+		t.Errorf("wrong link for second request; got %s", rr.Body.String())
+	}
+}
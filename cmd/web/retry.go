@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"time"
+)
+
+// parseTemplateWithRetry calls parseTemplate, trying again with a growing
+// wait when it fails with a transient error, as networked filesystems
+// sometimes do, up to app.config.parseAttempts times in all.
+func (app *application) parseTemplateWithRetry(t, layout string) (*template.Template, error) {
+	backoff := app.config.parseBackoff
+
+	for attempt := 1; ; attempt++ {
+		tmpl, err := app.parseTemplateLimited(t, layout)
+		if err == nil || attempt >= app.config.parseAttempts || !isTransient(err) {
+			return tmpl, err
+		}
+
+		app.log().Warn("reading template, retrying", "template", t, "attempt", attempt, "attempts", app.config.parseAttempts, "err", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isTransient reports whether err might go away if the read is tried again.
+// Missing files, permission problems and syntax errors won't.
+func isTransient(err error) bool {
+	var pe *ParseError
+	switch {
+	case errors.As(err, &pe),
+		errors.Is(err, fs.ErrNotExist),
+		errors.Is(err, fs.ErrPermission):
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// flakyLoader returns err for the first failures attempts at loading a page.
+type flakyLoader struct {
+	failures int
+	err      error
+	attempts int
+}
+
+func (l *flakyLoader) Load(path string) ([]byte, error) {
+	if strings.HasSuffix(path, ".page.gohtml") {
+		l.attempts++
+		if l.attempts <= l.failures {
+			return nil, l.err
+		}
+	}
+	return diskLoader{}.Load(path)
+}
+
+func TestApplication_buildTemplateFromDiskRetries(t *testing.T) {
+	eio := &fs.PathError{Op: "read", Path: "about.page.gohtml", Err: syscall.EIO}
+
+	app := newTestApp()
+	loader := &flakyLoader{failures: 1, err: eio}
+	app.loader = loader
+
+	// by default, there is a single attempt
+	if _, err := app.buildTemplateFromDisk("about.page.gohtml"); !errors.Is(err, syscall.EIO) {
+		t.Fatalf("wanted the I/O error without retries; got %v", err)
+	}
+
+	loader.attempts = 0
+	app.config.parseAttempts = 3
+	if _, err := app.buildTemplateFromDisk("about.page.gohtml"); err != nil {
+		t.Fatalf("wanted success on the second try; got %v", err)
+	}
+	if loader.attempts != 2 {
+		t.Errorf("wanted 2 attempts; got %d", loader.attempts)
+	}
+
+	// missing files aren't retried
+	loader.attempts = 0
+	loader.err = &fs.PathError{Op: "open", Path: "about.page.gohtml", Err: fs.ErrNotExist}
+	if _, err := app.buildTemplateFromDisk("about.page.gohtml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("wanted ErrNotExist; got %v", err)
+	}
+	if loader.attempts != 1 {
+		t.Errorf("missing file tried %d times", loader.attempts)
+	}
+}
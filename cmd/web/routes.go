@@ -10,14 +10,16 @@ import (
 
 func(app *application) routes() http.Handler {
 	mux := chi.NewRouter()
-     mux.Use(middleware.Recoverer)
-	 mux.Use(middleware.Timeout(60 * time.Second))
+     mux.Use(app.middleware()...)
 	 fileServer :=http.FileServer(http.Dir("./static/"))
 	 mux.Handle("/static/*", http.StripPrefix("/static", fileServer))
+	 mux.Get("/rendered/*", app.ServeRendered)
+	 mux.Get("/sitemap.xml", app.errorPages.Handle(app.Sitemap))
+
+	 // flip page feature flags at runtime
+	 mux.Post("/admin/features/{template}", app.errorPages.Handle(app.SetFeature))
 
 
-	 //display our test page
-	 mux.Get("/test-patterns", app.TestPatterns)
 	 mux.Get("/api/dog-from-factory", app.CreateDogFromFactory)
 	 mux.Get("/api/cat-from-factory", app.CreateCatFromFactory)
 	 	 mux.Get("/api/dog-from-abstract-factory", app.CreateDogFromAbstractFactory)
@@ -26,9 +28,27 @@ func(app *application) routes() http.Handler {
 	 // builder routes
 	 mux.Get("/api/dog-from-builder", app.CreateDogWithBuilder)
 
-	 mux.Get("/", app.ShowHome)
-	 mux.Get("/{page}", app.ShowPage)
+	 // pages declared with RegisterPage, including the home and test pages
+	 for _, p := range app.pages {
+	 	mux.Get(p.path, app.pageHandler(p))
+	 }
+
+	 mux.Get("/{page}", app.errorPages.Handle(app.ShowPage))
 	mux.Get("/api/dog-breeds", app.GetAllDogBreedsJSON)
 
 	return  mux
+}
+
+// middleware returns the middleware every request passes through, outermost
+// first. routes and RenderTestRequest both use it, so tests see the same
+// stack as production.
+func (app *application) middleware() []func(http.Handler) http.Handler {
+	return []func(http.Handler) http.Handler{
+		middleware.Recoverer,
+		middleware.Timeout(60 * time.Second),
+		app.Version,
+		app.RememberMe,
+		app.Experiments,
+		app.Flash,
+	}
 }
\ No newline at end of file
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// dataSchemas records the keys each template requires in td.Data. Templates
+// without a schema are not checked.
+type dataSchemas struct {
+	mu       sync.RWMutex
+	required map[string][]string
+}
+
+// RequireData declares that template t must be given each of keys. Missing
+// keys are reported before execution in development; in production the
+// check is skipped.
+func (app *application) RequireData(t string, keys ...string) {
+	app.schemas.mu.Lock()
+	defer app.schemas.mu.Unlock()
+
+	if app.schemas.required == nil {
+		app.schemas.required = make(map[string][]string)
+	}
+	app.schemas.required[t] = append(app.schemas.required[t], keys...)
+}
+
+// validateData checks td against the schema for template t, returning an
+// error naming the first missing key.
+func (app *application) validateData(t string, td *templateData) error {
+	if !app.isDevelopment() {
+		return nil
+	}
+
+	app.schemas.mu.RLock()
+	keys := app.schemas.required[t]
+	app.schemas.mu.RUnlock()
+
+	for _, key := range keys {
+		if _, ok := td.Data[key]; !ok {
+			return fmt.Errorf("template %s: missing required data key %q", t, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_RequireData(t *testing.T) {
+	app := newTestApp()
+	app.config.env = envDevelopment
+	app.RequireData("about.page.gohtml", "breed", "breeder")
+
+	req, _ := http.NewRequest("GET", "/about", nil)
+
+	// every required key present
+	rr := httptest.NewRecorder()
+	_, err := app.render(rr, req, "about.page.gohtml", &templateData{
+		Data: map[string]any{"breed": "beagle", "breeder": "Acme Kennels"},
+	})
+	if err != nil {
+		t.Errorf("satisfied schema failed: %s", err)
+	}
+
+	// a missing key names the template and the key
+	rr = httptest.NewRecorder()
+	_, err = app.render(rr, req, "about.page.gohtml", &templateData{
+		Data: map[string]any{"breed": "beagle"},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a missing key")
+	}
+	if !strings.Contains(err.Error(), "about.page.gohtml") || !strings.Contains(err.Error(), `"breeder"`) {
+		t.Errorf("error doesn't name the template and key: %s", err)
+	}
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("wrong response code; got %d, wanted 500", rr.Code)
+	}
+
+	// production skips the check
+	app.config.env = envProduction
+	if err := app.validateData("about.page.gohtml", &templateData{}); err != nil {
+		t.Errorf("schema checked in production: %s", err)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// scopedProvider is a DataProvider which only runs for paths under prefix.
+type scopedProvider struct {
+	prefix  string
+	provide DataProvider
+}
+
+// ProvideFor adds a DataProvider which only runs for requests whose path is
+// prefix or below it, so that, for instance, the admin navigation is only
+// loaded for /admin pages. Matching is by whole path segments: "/admin"
+// covers "/admin" and "/admin/users" but not "/administrators". Scoped
+// providers run after the unscoped ones, in the order they were added.
+func (app *application) ProvideFor(prefix string, provide DataProvider) {
+	app.scopedProviders = append(app.scopedProviders, scopedProvider{prefix: prefix, provide: provide})
+}
+
+// matches reports whether the provider applies to r.
+func (p scopedProvider) matches(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(p.prefix, "/")
+	path := r.URL.Path
+
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestApplication_ProvideFor(t *testing.T) {
+	app := application{}
+
+	var adminRuns int
+	app.ProvideFor("/admin", func(r *http.Request) map[string]any {
+		adminRuns++
+		return map[string]any{"AdminNav": []string{"users", "features"}, "nav": "admin"}
+	})
+	app.ProvideFor("/admin/users", func(r *http.Request) map[string]any {
+		return map[string]any{"nav": "users"}
+	})
+
+	tests := []struct {
+		path      string
+		wantAdmin bool
+		wantNav   any
+	}{
+		{"/", false, nil},
+		{"/administrators", false, nil},
+		{"/admin", true, "admin"},
+		{"/admin/features", true, "admin"},
+		// both match, and the later one wins
+		{"/admin/users/7", true, "users"},
+	}
+
+	for _, tt := range tests {
+		adminRuns = 0
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		td := app.composeData(req, &templateData{})
+
+		_, hasNav := td.Data["AdminNav"]
+		if hasNav != tt.wantAdmin || (adminRuns == 1) != tt.wantAdmin {
+			t.Errorf("%s: admin provider ran %d times, wanted it to run: %v", tt.path, adminRuns, tt.wantAdmin)
+		}
+		if td.Data["nav"] != tt.wantNav {
+			t.Errorf("%s: wrong nav; got %v, wanted %v", tt.path, td.Data["nav"], tt.wantNav)
+		}
+	}
+}
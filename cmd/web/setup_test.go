@@ -2,6 +2,8 @@ package main
 
 import (
 	"go-breeders/configuration"
+	"go-breeders/models"
+	"html/template"
 
 	"os"
 	"testing"
@@ -11,8 +13,32 @@ var testApp application
 
 func TestMain(m *testing.M) {
 	testApp = application{
-		App: configuration.New(nil),
+		App: &configuration.Application{Models: models.NewTest()},
 	}
 
 	os.Exit(m.Run())
 }
+
+// newTestApp returns an application which reads its templates from
+// testdata, sharing the models of testApp.
+func newTestApp() *application {
+	app := &application{
+		cache:       newMapCache(),
+		config:      appConfig{templateDir: "./testdata/templates"},
+		App:         testApp.App,
+	}
+	app.errorPages = newErrorResponder(app)
+
+	return app
+}
+
+// mustTemplate builds the template t from disk, failing the test on error.
+func mustTemplate(t *testing.T, app *application, name string) *template.Template {
+	t.Helper()
+
+	tmpl, err := app.buildTemplateFromDisk(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// renderStats holds counters accumulated by render over the lifetime of the
+// application. They are written to the log when the application shuts down.
+type renderStats struct {
+	renders     atomic.Int64
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+	errors      atomic.Int64
+
+	// bytesRendered counts output before compression, bytesSent after
+	bytesRendered atomic.Int64
+	bytesSent     atomic.Int64
+}
+
+// flush writes the accumulated render stats to logger.
+func (s *renderStats) flush(logger *slog.Logger) {
+	logger.Info("render stats",
+		"renders", s.renders.Load(),
+		"cache_hits", s.cacheHits.Load(),
+		"cache_misses", s.cacheMisses.Load(),
+		"errors", s.errors.Load(),
+		"bytes_rendered", s.bytesRendered.Load(),
+		"bytes_sent", s.bytesSent.Load(),
+	)
+}
+
+// Shutdown stops background goroutines, flushes render stats to the log and
+// clears the template cache. Only the first call does any work; later calls
+// return nil immediately. If ctx expires before the background goroutines
+// have stopped, the stats are still flushed, the cache is left as it is and
+// the context's error is returned.
+func (app *application) Shutdown(ctx context.Context) error {
+	var err error
+	app.shutdownOnce.Do(func() {
+		err = app.shutdown(ctx)
+	})
+	return err
+}
+
+func (app *application) shutdown(ctx context.Context) error {
+	if app.quit != nil {
+		close(app.quit)
+	}
+
+	// wait for background goroutines, but no longer than ctx allows
+	stopped := make(chan struct{})
+	go func() {
+		app.background.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		// Shutdown can't be retried, so this is the last chance to
+		// report the stats.
+		app.stats.flush(app.log())
+		return ctx.Err()
+	}
+
+	app.stats.flush(app.log())
+
+	app.clearCache()
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestApplication_Shutdown(t *testing.T) {
+	app := application{cache: newMapCache(), quit: make(chan struct{})}
+	app.cacheTemplate("home.page.gohtml", template.New("home"))
+
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error on first shutdown: %s", err)
+	}
+
+	if _, ok := app.cachedTemplate("home.page.gohtml"); ok {
+		t.Error("template cache was not cleared")
+	}
+
+	// a second call must not do anything, so the cache should stay as it is
+	app.cacheTemplate("about.page.gohtml", template.New("about"))
+
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected error on second shutdown: %s", err)
+	}
+
+	if _, ok := app.cachedTemplate("about.page.gohtml"); !ok {
+		t.Error("second call to Shutdown was not a no-op")
+	}
+}
+
+func TestApplication_ShutdownDeadline(t *testing.T) {
+	app := application{quit: make(chan struct{})}
+
+	// a background goroutine that doesn't finish before the test's end,
+	// when it is released so Shutdown's wait can return
+	app.background.Add(1)
+	t.Cleanup(app.background.Done)
+
+	var out bytes.Buffer
+	app.logger = slog.New(slog.NewTextHandler(&out, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := app.Shutdown(ctx); err != context.Canceled {
+		t.Errorf("wrong error; got %v, wanted %v", err, context.Canceled)
+	}
+
+	// the stats are flushed even though shutdown gave up waiting
+	if !strings.Contains(out.String(), "render stats") {
+		t.Errorf("render stats were not flushed; got %q", out.String())
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errNoBaseURL is returned by Sitemap in production when no base URL is
+// configured.
+var errNoBaseURL = errors.New("sitemap: no base URL configured")
+
+// sitemapMeta is the optional sitemap metadata of a registered page.
+// Priority runs from 0 to 1 and is left out when nil; ChangeFreq is one of
+// always, hourly, daily, weekly, monthly, yearly or never.
+type sitemapMeta struct {
+	Priority   *float64
+	ChangeFreq string
+}
+
+// SetSitemapMeta sets the sitemap metadata of the page registered at path.
+func (app *application) SetSitemapMeta(path string, meta sitemapMeta) error {
+	if p := meta.Priority; p != nil && (*p < 0 || *p > 1) {
+		return fmt.Errorf("sitemap metadata for %s: priority %v out of range", path, *p)
+	}
+
+	for i := range app.pages {
+		if app.pages[i].path == path {
+			app.pages[i].sitemap = meta
+			return nil
+		}
+	}
+	return fmt.Errorf("sitemap metadata for %s: %w", path, errNotFound)
+}
+
+// formatPriority formats a sitemap priority with as many decimals as it
+// needs, but at least one, so 0.25 stays 0.25 and 1 becomes 1.0.
+func formatPriority(p float64) string {
+	s := strconv.FormatFloat(p, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// sitemapURLSet and sitemapURL are the XML of a sitemap, as described at
+// https://www.sitemaps.org/protocol.html.
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+// renderSitemap writes a sitemap of the public registered pages, with
+// locations relative to baseURL. Pages under /admin, drafts and pages
+// switched off by a feature flag are left out.
+func (app *application) renderSitemap(w http.ResponseWriter, baseURL string) error {
+	set := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	base := strings.TrimSuffix(baseURL, "/")
+
+	for _, p := range app.pages {
+		if !app.inSitemap(p) {
+			continue
+		}
+
+		u := sitemapURL{Loc: base + p.path, ChangeFreq: p.sitemap.ChangeFreq}
+		if p.sitemap.Priority != nil {
+			u.Priority = formatPriority(*p.sitemap.Priority)
+		}
+		set.URLs = append(set.URLs, u)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(set); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// inSitemap reports whether p is public.
+func (app *application) inSitemap(p page) bool {
+	switch {
+	case p.path == "/admin" || strings.HasPrefix(p.path, "/admin/"):
+		return false
+	case isDraft(p.template):
+		return false
+	case app.features != nil && !app.features.Allow(p.template):
+		return false
+	default:
+		return true
+	}
+}
+
+// Sitemap serves /sitemap.xml. Locations use the configured base URL. Only
+// in development is the host the request was made to used instead: the Host
+// header comes from the client, and a cache in front of the site would keep
+// a sitemap pointing wherever it said.
+func (app *application) Sitemap(w http.ResponseWriter, r *http.Request) error {
+	baseURL := app.config.baseURL
+	if baseURL == "" {
+		if !app.isDevelopment() {
+			return errNoBaseURL
+		}
+
+		scheme := "https"
+		if r.TLS == nil {
+			scheme = "http"
+		}
+		baseURL = scheme + "://" + r.Host
+	}
+
+	return app.renderSitemap(w, baseURL)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplication_renderSitemap(t *testing.T) {
+	app := newTestApp()
+	app.features = newFlagGate([]string{"account.page.gohtml"})
+
+	for _, p := range []struct{ path, template string }{
+		{"/about", "about.page.gohtml"},
+		{"/breeds", "breeds.page.gohtml"},
+		{"/admin/dashboard", "dashboard.page.gohtml"},
+		{"/about-draft", "about.draft.gohtml"},
+		{"/account", "account.page.gohtml"},
+	} {
+		if err := app.RegisterPage(p.path, p.template, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	high, zero, tooHigh := 0.75, 0.0, 1.5
+	if err := app.SetSitemapMeta("/about", sitemapMeta{Priority: &high, ChangeFreq: "monthly"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.SetSitemapMeta("/breeds", sitemapMeta{Priority: &zero}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.SetSitemapMeta("/breeds", sitemapMeta{Priority: &tooHigh}); err == nil {
+		t.Error("expected an error for a priority over 1")
+	}
+	if err := app.SetSitemapMeta("/missing", sitemapMeta{}); err == nil {
+		t.Error("expected an error setting metadata for an unregistered page")
+	}
+
+	rr := httptest.NewRecorder()
+	if err := app.renderSitemap(rr, "https://breeders.example.com/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rr.Header().Get("Content-Type"); got != "application/xml; charset=utf-8" {
+		t.Errorf("wrong Content-Type; got %q", got)
+	}
+
+	var got struct {
+		XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+		URLs    []struct {
+			Loc        string `xml:"loc"`
+			ChangeFreq string `xml:"changefreq"`
+			Priority   string `xml:"priority"`
+		} `xml:"url"`
+	}
+	if err := xml.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid sitemap: %v\n%s", err, rr.Body.String())
+	}
+
+	var locs []string
+	for _, u := range got.URLs {
+		locs = append(locs, u.Loc)
+	}
+	want := []string{"https://breeders.example.com/about", "https://breeders.example.com/breeds"}
+	if !reflect.DeepEqual(locs, want) {
+		t.Errorf("wrong locations; got %v, wanted %v", locs, want)
+	}
+	if len(got.URLs) > 0 && (got.URLs[0].Priority != "0.75" || got.URLs[0].ChangeFreq != "monthly") {
+		t.Errorf("metadata missing; got %+v", got.URLs[0])
+	}
+	if len(got.URLs) > 1 && (got.URLs[1].Priority != "0.0" || got.URLs[1].ChangeFreq != "") {
+		t.Errorf("wanted only a priority of 0; got %+v", got.URLs[1])
+	}
+}
+
+func TestApplication_Sitemap(t *testing.T) {
+	app := newTestApp()
+	if err := app.RegisterPage("/about", "about.page.gohtml", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://localhost:8080/sitemap.xml", nil)
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+		return rr
+	}
+
+	// in production the Host header is never trusted
+	if rr := get(); rr.Code != http.StatusInternalServerError || strings.Contains(rr.Body.String(), "localhost") {
+		t.Errorf("wanted a 500 without a base URL in production; got %d %s", rr.Code, rr.Body.String())
+	}
+
+	app.config.baseURL = "https://breeders.example.com"
+	if rr := get(); !strings.Contains(rr.Body.String(), "<loc>https://breeders.example.com/about</loc>") {
+		t.Errorf("base URL not used; got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// in development it is, for convenience
+	app.config.baseURL = ""
+	app.config.env = envDevelopment
+	rr := get()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("wrong status; got %d", rr.Code)
+	}
+	if want := "<loc>http://localhost:8080/about</loc>"; !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("wanted %s in %s", want, rr.Body.String())
+	}
+}
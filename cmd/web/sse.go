@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// errSSEEventType is returned by writeSSE for an event type which would
+// break out of its field.
+var errSSEEventType = errors.New("sse: event type contains a line break")
+
+// SSEEvent is a single server-sent event. The named Fragment (a template
+// declared with {{define}}) of the page template Template is rendered with
+// Data and sent as the event's data. Event is optional, and sets the event
+// type seen by the browser's EventSource.
+type SSEEvent struct {
+	Event    string
+	Template string
+	Fragment string
+	Data     *templateData
+}
+
+// renderSSE streams events to the client as server-sent events until the
+// events channel is closed or the client goes away. Each event is rendered
+// and flushed as soon as it arrives. Events that fail to render, or whose
+// type contains a line break, are logged and skipped.
+func (app *application) renderSSE(w http.ResponseWriter, r *http.Request, events <-chan SSEEvent) {
+	flusher, ok := flusherOf(w)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			var buf bytes.Buffer
+			if err := app.renderFragment(&buf, r, event.Template, event.Fragment, event.Data); err != nil {
+				app.log().Error("rendering event", "template", event.Template, "fragment", event.Fragment, "err", err)
+				continue
+			}
+
+			err := writeSSE(w, event.Event, buf.Bytes())
+			if errors.Is(err, errSSEEventType) {
+				app.log().Error("sending event", "err", err)
+				continue
+			}
+			if err != nil {
+				// the client has gone away
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// renderFragment executes only the named fragment of the page template t,
+// writing the result to w. The request functions are bound to r.
+func (app *application) renderFragment(w io.Writer, r *http.Request, t, fragment string, td *templateData) error {
+	tmpl, err := app.getTemplate(t)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err = app.bindRequest(tmpl, r)
+	if err != nil {
+		return err
+	}
+
+	if td == nil {
+		td = &templateData{}
+	}
+
+	return tmpl.ExecuteTemplate(w, fragment, td)
+}
+
+// writeSSE writes one event frame. Every line of data gets its own data:
+// field, as the format requires; the browser joins them back with newlines.
+// Like the browser, it takes "\r\n", "\r" and "\n" all as line breaks. An
+// event type containing one is rejected with errSSEEventType, as it could
+// otherwise add fields of its own to the frame.
+func writeSSE(w io.Writer, event string, data []byte) error {
+	if strings.ContainsAny(event, "\r\n") {
+		return fmt.Errorf("%w: %q", errSSEEventType, event)
+	}
+
+	var buf bytes.Buffer
+
+	if event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event)
+	}
+
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	if len(data) > 0 {
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			fmt.Fprintf(&buf, "data: %s\n", line)
+		}
+	}
+	buf.WriteString("\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_renderSSE(t *testing.T) {
+	app := newTestApp()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events := make(chan SSEEvent, 2)
+		for _, count := range []int{1, 2} {
+			events <- SSEEvent{
+				Event:    "stat",
+				Template: "dashboard.page.gohtml",
+				Fragment: "stat",
+				Data:     &templateData{Data: map[string]any{"count": count}},
+			}
+		}
+		close(events)
+
+		app.renderSSE(w, r, events)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("wrong content type; got %s", ct)
+	}
+
+	// read the frames, which are separated by a blank line
+	var frames []string
+	var frame []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			frames = append(frames, strings.Join(frame, "\n"))
+			frame = nil
+			continue
+		}
+		frame = append(frame, line)
+	}
+
+	if len(frames) != 2 {
+		t.Fatalf("wrong number of events; got %d, wanted 2: %q", len(frames), frames)
+	}
+
+	for i, want := range []string{"<span>1</span>", "<span>2</span>"} {
+		if !strings.HasPrefix(frames[i], "event: stat\n") {
+			t.Errorf("event %d: missing event type; got %q", i, frames[i])
+		}
+		if !strings.Contains(frames[i], "data: "+want) {
+			t.Errorf("event %d: missing rendered fragment %s; got %q", i, want, frames[i])
+		}
+	}
+}
+
+func TestWriteSSE(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+
+	tests := []struct {
+		name  string
+		event string
+		data  string
+		want  string
+	}{
+		{"lines", "stat", "a\nb\n", "event: stat\ndata: a\ndata: b\n\n"},
+		{"carriage returns", "", "a\r\nb\rc", "data: a\ndata: b\ndata: c\n\n"},
+		{"blank line kept", "", "a\n\nb", "data: a\ndata: \ndata: b\n\n"},
+		{"long line", "", long, "data: " + long + "\n\n"},
+		{"no data", "ping", "", "event: ping\n\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeSSE(&buf, tt.event, []byte(tt.data)); err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: wrong frame; got %.80q, wanted %.80q", tt.name, got, tt.want)
+		}
+	}
+
+	// an event type with a line break could inject fields
+	for _, event := range []string{"stat\ndata: injected", "stat\rid: 1"} {
+		var buf bytes.Buffer
+		if err := writeSSE(&buf, event, []byte("a")); !errors.Is(err, errSSEEventType) {
+			t.Errorf("%q: expected errSSEEventType; got %v", event, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%q: rejected event was written: %q", event, buf.String())
+		}
+	}
+}
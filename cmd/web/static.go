@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// errNoRenderedDir is returned by renderToFile when no rendered pages
+// directory is configured.
+var errNoRenderedDir = errors.New("no rendered pages directory configured")
+
+// renderToFile renders the template t into the file name in the rendered
+// pages directory, so it can be served by ServeRendered without executing
+// the template on every request. Without a directory, it fails with
+// errNoRenderedDir rather than write relative to the working directory.
+func (app *application) renderToFile(r *http.Request, t string, td *templateData, name string) error {
+	if app.config.renderedDir == "" {
+		return errNoRenderedDir
+	}
+
+	file, err := os.Create(app.renderedPath(name))
+	if err != nil {
+		return err
+	}
+
+	if _, err := app.renderTo(file, r, t, td); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// renderedPath returns the location of name in the rendered pages
+// directory. name is cleaned as an absolute path first, so it can't
+// climb out of the directory.
+func (app *application) renderedPath(name string) string {
+	return filepath.Join(app.config.renderedDir, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+// ServeRendered serves a file from the rendered pages directory. Range
+// requests are supported, so browsers and CDNs can fetch large documents in
+// parts: a satisfiable Range gets 206 Partial Content, an unsatisfiable one
+// 416, and a request without one the whole file.
+//
+// Clients which accept gzip get a precompressed sibling of the file, such
+// as about.html.gz for about.html, when there is one.
+func (app *application) ServeRendered(w http.ResponseWriter, r *http.Request) {
+	if app.config.renderedDir == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	name := app.renderedPath(chi.URLParam(r, "*"))
+
+	// Whether or not a compressed file is served, the response depends on
+	// Accept-Encoding.
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	var (
+		file *os.File
+		info os.FileInfo
+		ok   bool
+	)
+	if acceptsGzip(r) {
+		if file, info, ok = openRendered(name + ".gz"); ok {
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+	if !ok {
+		if file, info, ok = openRendered(name); !ok {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	defer file.Close()
+
+	// ServeContent handles Range and If-Range. Accept-Ranges is set here
+	// rather than left to it, so that it's also sent with a 416. The
+	// uncompressed name is passed so the Content-Type is that of the page.
+	w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeContent(w, r, filepath.Base(name), info.ModTime(), file)
+}
+
+// openRendered opens the regular file at name.
+func openRendered(name string) (*os.File, os.FileInfo, bool) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		_ = file.Close()
+		return nil, nil, false
+	}
+
+	return file, info, true
+}
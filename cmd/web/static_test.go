@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplication_ServeRendered(t *testing.T) {
+	app := newTestApp()
+
+	// nowhere to write to
+	req, _ := http.NewRequest("GET", "/about", nil)
+	if err := app.renderToFile(req, "about.page.gohtml", nil, "about.html"); !errors.Is(err, errNoRenderedDir) {
+		t.Fatalf("wanted errNoRenderedDir without a directory; got %v", err)
+	}
+
+	app.config.renderedDir = t.TempDir()
+	if err := app.renderToFile(req, "about.page.gohtml", nil, "about.html"); err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := os.ReadFile(filepath.Join(app.config.renderedDir, "about.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(full), "<main>about</main>") {
+		t.Fatalf("page not rendered to file; got %s", full)
+	}
+
+	tests := []struct {
+		name       string
+		rangeHdr   string
+		wantStatus int
+		wantBody   string
+	}{
+		{"no range", "", http.StatusOK, string(full)},
+		{"valid range", "bytes=0-14", http.StatusPartialContent, string(full[:15])},
+		{"unsatisfiable range", "bytes=100000-", http.StatusRequestedRangeNotSatisfiable, ""},
+	}
+
+	mux := app.routes()
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/rendered/about.html", nil)
+		if tt.rangeHdr != "" {
+			req.Header.Set("Range", tt.rangeHdr)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s: wrong status; got %d, wanted %d", tt.name, rr.Code, tt.wantStatus)
+		}
+		if rr.Header().Get("Accept-Ranges") != "bytes" {
+			t.Errorf("%s: Accept-Ranges not set", tt.name)
+		}
+		if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+			t.Errorf("%s: wrong body; got %q, wanted %q", tt.name, rr.Body.String(), tt.wantBody)
+		}
+	}
+
+	// paths are kept inside the rendered directory
+	req, _ = http.NewRequest("GET", "/rendered/../testdata/templates/about.page.gohtml", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("path traversal not prevented; got %d", rr.Code)
+	}
+}
+
+func TestApplication_ServeRenderedPrecompressed(t *testing.T) {
+	app := newTestApp()
+	app.config.renderedDir = t.TempDir()
+
+	plain := []byte("<main>about</main>")
+	compressed, err := gzipBytes(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(app.config.renderedDir, "about.html"), plain, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(app.config.renderedDir, "about.html.gz"), compressed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(app.config.renderedDir, "contact.html"), plain, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		acceptGzip   bool
+		wantEncoding string
+		wantBody     []byte
+	}{
+		{"accepts gzip", "/rendered/about.html", true, "gzip", compressed},
+		{"no gzip", "/rendered/about.html", false, "", plain},
+		{"no precompressed file", "/rendered/contact.html", true, "", plain},
+	}
+
+	mux := app.routes()
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		if tt.acceptGzip {
+			req.Header.Set("Accept-Encoding", "gzip, deflate")
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: wrong status; got %d", tt.name, rr.Code)
+		}
+		if got := rr.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+			t.Errorf("%s: wrong Content-Encoding; got %q, wanted %q", tt.name, got, tt.wantEncoding)
+		}
+		if got := rr.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("%s: wrong Vary; got %q", tt.name, got)
+		}
+		if got := rr.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+			t.Errorf("%s: wrong Content-Type; got %q", tt.name, got)
+		}
+		if !bytes.Equal(rr.Body.Bytes(), tt.wantBody) {
+			t.Errorf("%s: wrong body; got %q", tt.name, rr.Body.Bytes())
+		}
+	}
+}
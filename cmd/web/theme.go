@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const themeCookieName = "theme"
+
+// theme returns the colour theme for r: the theme cookie, then the
+// Sec-CH-Prefers-Color-Scheme client hint (only sent by browsers which have
+// been asked for it with Accept-CH), then the configured default. Values not
+// in app.config.themes are ignored, so a bad cookie falls back rather than
+// failing the render.
+func (app *application) theme(r *http.Request) string {
+	if cookie, err := r.Cookie(themeCookieName); err == nil && app.validTheme(cookie.Value) {
+		return cookie.Value
+	}
+
+	// structured header values are quoted, e.g. "dark"
+	hint := strings.Trim(r.Header.Get("Sec-CH-Prefers-Color-Scheme"), `"`)
+	if app.validTheme(hint) {
+		return hint
+	}
+
+	return app.config.defaultTheme
+}
+
+func (app *application) validTheme(theme string) bool {
+	return theme != "" && slices.Contains(app.config.themes, theme)
+}
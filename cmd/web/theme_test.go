@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestApplication_theme(t *testing.T) {
+	app := application{
+		config: appConfig{themes: []string{"light", "dark"}, defaultTheme: "light"},
+	}
+
+	tests := []struct {
+		name   string
+		cookie string
+		hint   string
+		want   string
+	}{
+		{"default", "", "", "light"},
+		{"client hint", "", `"dark"`, "dark"},
+		{"cookie", "dark", `"light"`, "dark"},
+		{"invalid cookie", "neon", "", "light"},
+		{"invalid hint", "", `"neon"`, "light"},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if tt.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: themeCookieName, Value: tt.cookie})
+		}
+		if tt.hint != "" {
+			req.Header.Set("Sec-CH-Prefers-Color-Scheme", tt.hint)
+		}
+
+		if got := app.defaultData(req)["Theme"]; got != tt.want {
+			t.Errorf("%s: wrong theme; got %v, wanted %s", tt.name, got, tt.want)
+		}
+	}
+}
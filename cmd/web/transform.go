@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+)
+
+// Transform rewrites a page's rendered HTML before it is written out.
+// Transforms run in the order they appear in app.transforms, then in
+// app.documentTransforms, each getting the output of the one before, and
+// the first to fail aborts the render.
+type Transform func(html []byte, r *http.Request) ([]byte, error)
+
+// applyTransforms runs the application's transforms, followed by its
+// document transforms, over html, a whole page.
+func (app *application) applyTransforms(html []byte, r *http.Request) ([]byte, error) {
+	html, err := app.applySectionTransforms(html, r)
+	if err != nil {
+		return nil, err
+	}
+	return app.applyDocumentTransforms(html, r)
+}
+
+// applySectionTransforms runs only app.transforms over html, which may be
+// just part of a page. These must work on any part split off at a template
+// boundary: FingerprintAssets does, as an attribute can't span templates,
+// while StripComments doesn't, as a comment or script can.
+func (app *application) applySectionTransforms(html []byte, r *http.Request) ([]byte, error) {
+	for i, transform := range app.transforms {
+		var err error
+		if html, err = transform(html, r); err != nil {
+			return nil, fmt.Errorf("transform %d: %w", i, err)
+		}
+	}
+	return html, nil
+}
+
+// applyDocumentTransforms runs app.documentTransforms over html, a whole
+// page.
+func (app *application) applyDocumentTransforms(html []byte, r *http.Request) ([]byte, error) {
+	for i, transform := range app.documentTransforms {
+		var err error
+		if html, err = transform(html, r); err != nil {
+			return nil, fmt.Errorf("document transform %d: %w", i, err)
+		}
+	}
+	return html, nil
+}
+
+// InjectBefore returns a Transform which inserts snippet in front of the
+// last occurrence of closing, such as "</body>" for an analytics script.
+// Pages without closing are left alone.
+func InjectBefore(closing, snippet string) Transform {
+	return func(html []byte, r *http.Request) ([]byte, error) {
+		i := bytes.LastIndex(html, []byte(closing))
+		if i < 0 {
+			return html, nil
+		}
+
+		out := make([]byte, 0, len(html)+len(snippet))
+		out = append(out, html[:i]...)
+		out = append(out, snippet...)
+		out = append(out, html[i:]...)
+		return out, nil
+	}
+}
+
+// FingerprintAssets returns a Transform which replaces the logical asset
+// paths in m with their fingerprinted ones wherever they appear as a quoted
+// attribute value, so templates can keep referring to /static/css/site.css.
+func FingerprintAssets(m *assetManifest) Transform {
+	return func(html []byte, r *http.Request) ([]byte, error) {
+		if m == nil {
+			return html, nil
+		}
+
+		for logical, fingerprinted := range m.Assets {
+			html = bytes.ReplaceAll(html, []byte(`"`+logical+`"`), []byte(`"`+fingerprinted+`"`))
+		}
+		return html, nil
+	}
+}
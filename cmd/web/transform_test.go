@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_renderTransforms(t *testing.T) {
+	app := newTestApp()
+	app.transforms = []Transform{
+		FingerprintAssets(&assetManifest{Assets: map[string]string{"/static/site.css": "/static/site.3f2a1c.css"}}),
+		InjectBefore("</body>", `<link href="/static/site.css">`),
+		// runs last, so sees the output of both
+		func(html []byte, r *http.Request) ([]byte, error) {
+			return bytes.ReplaceAll(html, []byte("about"), []byte("ABOUT")), nil
+		},
+	}
+
+	req, _ := http.NewRequest("GET", "/about", nil)
+	rr := httptest.NewRecorder()
+	if _, err := app.render(rr, req, "about.page.gohtml", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	body := rr.Body.String()
+	if !strings.Contains(body, `<main>ABOUT</main>`) {
+		t.Errorf("last transform not applied; got %s", body)
+	}
+	// injected after fingerprinting, so left alone
+	if !strings.Contains(body, `<link href="/static/site.css"></body>`) {
+		t.Errorf("snippet not injected in order; got %s", body)
+	}
+}
+
+func TestApplication_renderFailingTransform(t *testing.T) {
+	app := newTestApp()
+
+	ran := false
+	app.transforms = []Transform{
+		func([]byte, *http.Request) ([]byte, error) { return nil, errors.New("minifier broke") },
+		func(html []byte, r *http.Request) ([]byte, error) { ran = true; return html, nil },
+	}
+
+	req, _ := http.NewRequest("GET", "/about", nil)
+	rr := httptest.NewRecorder()
+	if _, err := app.render(rr, req, "about.page.gohtml", nil); err == nil {
+		t.Fatal("expected the render to fail")
+	}
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("wrong status; got %d", rr.Code)
+	}
+	if strings.Contains(rr.Body.String(), "<main>about</main>") {
+		t.Error("page written despite a failing transform")
+	}
+	if ran {
+		t.Error("transform after a failure still ran")
+	}
+}
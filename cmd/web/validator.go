@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+)
+
+// HTMLValidator checks a rendered page, returning a description of each
+// problem found; none means the page passed.
+type HTMLValidator func(t string, html []byte) []string
+
+// validateHTML runs app.htmlValidator over the output of the template t in
+// development, logging each violation with the template's name. It never
+// fails the render: the page is still sent, so the developer can see it.
+func (app *application) validateHTML(t string, html []byte) {
+	if app.htmlValidator == nil || !app.isDevelopment() {
+		return
+	}
+
+	for _, violation := range app.htmlValidator(t, html) {
+		app.log().LogAttrs(context.Background(), slog.LevelWarn, "invalid HTML",
+			slog.String("template", t),
+			slog.String("violation", violation),
+		)
+	}
+}
+
+var (
+	ampHTMLTag     = regexp.MustCompile(`(?i)<html[^>]*\s(amp|⚡)[\s>=]`)
+	ampRuntime     = regexp.MustCompile(`(?i)<script[^>]+src="https://cdn\.ampproject\.org/v0\.js"`)
+	ampScriptTag   = regexp.MustCompile(`(?i)<script\b[^>]*>`)
+	ampAllowedJS   = regexp.MustCompile(`(?i)src="https://cdn\.ampproject\.org/|type="application/(ld\+)?json"`)
+	ampBannedTag   = regexp.MustCompile(`(?i)<(img|video|audio|iframe|form|frame|object|embed)\b`)
+	ampStyleAttr   = regexp.MustCompile(`(?i)<[a-z][^>]*\sstyle\s*=`)
+	ampCustomStyle = regexp.MustCompile(`(?i)<style\b[^>]*>`)
+)
+
+// AMPValidator is an HTMLValidator covering the AMP rules most often broken
+// by hand-written templates. It is not the full AMP validator, which should
+// still be run before release, but catches the common mistakes early:
+// a missing amp attribute or runtime, custom scripts, tags AMP replaces with
+// its own components, inline style attributes, and style elements other
+// than amp-custom and amp-boilerplate.
+func AMPValidator(t string, html []byte) []string {
+	var violations []string
+
+	if !ampHTMLTag.Match(html) {
+		violations = append(violations, "html element lacks the amp attribute")
+	}
+	if !ampRuntime.Match(html) {
+		violations = append(violations, "AMP runtime script missing")
+	}
+
+	for _, tag := range ampScriptTag.FindAll(html, -1) {
+		if !ampAllowedJS.Match(tag) {
+			violations = append(violations, fmt.Sprintf("custom script not allowed: %s", tag))
+		}
+	}
+	for _, m := range ampBannedTag.FindAllSubmatch(html, -1) {
+		violations = append(violations, fmt.Sprintf("<%s> must be replaced by its amp- component", bytes.ToLower(m[1])))
+	}
+	if ampStyleAttr.Match(html) {
+		violations = append(violations, "inline style attributes are not allowed")
+	}
+	for _, tag := range ampCustomStyle.FindAll(html, -1) {
+		if !bytes.Contains(tag, []byte("amp-custom")) && !bytes.Contains(tag, []byte("amp-boilerplate")) {
+			violations = append(violations, fmt.Sprintf("style element must be amp-custom: %s", tag))
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplication_validateHTML(t *testing.T) {
+	var buf bytes.Buffer
+
+	app := newTestApp()
+	app.config.env = envDevelopment
+	app.logger = slog.New(slog.NewTextHandler(&buf, nil))
+	app.htmlValidator = AMPValidator
+
+	valid := `<!doctype html><html amp lang="en"><head>` +
+		`<script async src="https://cdn.ampproject.org/v0.js"></script>` +
+		`<style amp-custom>main{color:#333}</style>` +
+		`<script type="application/ld+json">{}</script>` +
+		`</head><body><main><amp-img src="/dog.png" width="1" height="1"></amp-img></main></body></html>`
+	invalid := `<!doctype html><html lang="en"><head><style>main{}</style></head>` +
+		`<body><script src="/app.js"></script><main style="color:red"><img src="/dog.png"></main></body></html>`
+
+	for name, src := range map[string]string{"valid.page.gohtml": valid, "invalid.page.gohtml": invalid} {
+		if err := app.SetTemplate(name, src); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	render := func(name string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/", nil)
+		rr := httptest.NewRecorder()
+		if _, err := app.render(rr, req, name, nil); err != nil {
+			t.Fatal(err)
+		}
+		return rr
+	}
+
+	render("valid.page.gohtml")
+	if buf.Len() != 0 {
+		t.Errorf("violations logged for a valid page:\n%s", buf.String())
+	}
+
+	// violations are logged, but the page is still sent
+	rr := render("invalid.page.gohtml")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "<main") {
+		t.Errorf("invalid page not sent; got %d %s", rr.Code, rr.Body.String())
+	}
+
+	logged := buf.String()
+	for _, want := range []string{
+		"lacks the amp attribute",
+		"runtime script missing",
+		"custom script not allowed",
+		"<img> must be replaced",
+		"inline style attributes",
+		"style element must be amp-custom",
+	} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("violation %q not logged", want)
+		}
+	}
+	if !strings.Contains(logged, "template=invalid.page.gohtml") {
+		t.Errorf("violations don't name the template:\n%s", logged)
+	}
+
+	// and nothing runs in production
+	buf.Reset()
+	app.config.env = envProduction
+	render("invalid.page.gohtml")
+	if buf.Len() != 0 {
+		t.Errorf("validator ran in production:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "net/http"
+
+// Build information, set at link time:
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildTime=$(date -u +%FT%TZ)" ./cmd/web
+//
+// Any left unset read as "dev", as in a plain go run.
+var (
+	version   string
+	commit    string
+	buildTime string
+)
+
+// buildInfo describes the running build, as .Data.Version in templates.
+type buildInfo struct {
+	Version string
+	Commit  string
+	Time    string
+}
+
+// currentBuild returns the build information set by -ldflags.
+func currentBuild() buildInfo {
+	return buildInfo{Version: version, Commit: commit, Time: buildTime}
+}
+
+// String formats b for the X-App-Version header, as "1.4.0 (3f2a1c9)".
+func (b buildInfo) String() string {
+	return b.Version + " (" + b.Commit + ")"
+}
+
+// buildInfo returns the application's build, with unset fields as "dev".
+func (app *application) buildInfo() buildInfo {
+	b := app.build
+	for _, field := range []*string{&b.Version, &b.Commit, &b.Time} {
+		if *field == "" {
+			*field = "dev"
+		}
+	}
+	return b
+}
+
+// Version is middleware which sets the X-App-Version header on every
+// response.
+func (app *application) Version(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-App-Version", app.buildInfo().String())
+		next.ServeHTTP(w, r)
+	})
+}
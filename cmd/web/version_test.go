@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestApplication_Version(t *testing.T) {
+	app := newTestApp()
+	app.build = buildInfo{Version: "1.4.0", Commit: "3f2a1c9", Time: "2026-10-14T09:00:00Z"}
+
+	if err := app.SetTemplate("version.page.gohtml", `<footer>{{.Data.Version.Version}} {{.Data.Version.Commit}} {{.Data.Version.Time}}</footer>`); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := app.RenderTestRequest("GET", "/version", nil)
+
+	if got := rr.Header().Get("X-App-Version"); got != "1.4.0 (3f2a1c9)" {
+		t.Errorf("wrong X-App-Version; got %q", got)
+	}
+	if got := rr.Body.String(); got != "<footer>1.4.0 3f2a1c9 2026-10-14T09:00:00Z</footer>" {
+		t.Errorf("wrong footer; got %q", got)
+	}
+
+	// an unstamped build reports itself as dev
+	app.build = buildInfo{}
+	rr = app.RenderTestRequest("GET", "/version", nil)
+	if got := rr.Header().Get("X-App-Version"); got != "dev (dev)" {
+		t.Errorf("wrong X-App-Version for a dev build; got %q", got)
+	}
+	if got := rr.Body.String(); got != "<footer>dev dev dev</footer>" {
+		t.Errorf("wrong footer for a dev build; got %q", got)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// warmRoute is a page rendered by WarmRender, with the data to render it with.
+type warmRoute = struct {
+	T  string
+	TD *templateData
+}
+
+// WarmRender renders each of routes once and throws the output away, so that
+// after a deploy the first real requests find the templates compiled and
+// cached, and template functions and data providers have already run once.
+func (app *application) WarmRender(routes []warmRoute) {
+	start := time.Now()
+
+	for _, route := range routes {
+		req, err := http.NewRequest("GET", "/", nil)
+		if err != nil {
+			app.log().Error("warming template", "template", route.T, "err", err)
+			continue
+		}
+
+		_, _ = app.render(&discardResponseWriter{header: make(http.Header)}, req, route.T, route.TD)
+	}
+
+	app.log().Info("warmed templates", "count", len(routes), "duration", time.Since(start))
+}
+
+// discardResponseWriter is an http.ResponseWriter which throws away
+// everything written to it.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (d *discardResponseWriter) Header() http.Header         { return d.header }
+func (d *discardResponseWriter) Write(b []byte) (int, error) { return io.Discard.Write(b) }
+func (d *discardResponseWriter) WriteHeader(int)             {}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestApplication_WarmRender(t *testing.T) {
+	app := newTestApp()
+
+	app.WarmRender([]warmRoute{
+		{T: "about.page.gohtml"},
+		{T: "account.page.gohtml", TD: &templateData{Data: map[string]any{"user": "fido"}}},
+	})
+
+	for _, name := range []string{"about.page.gohtml", "account.page.gohtml"} {
+		if _, ok := app.cachedTemplate(name); !ok {
+			t.Errorf("%s was not cached", name)
+		}
+	}
+
+	if app.stats.renders.Load() != 2 {
+		t.Errorf("wrong number of renders; got %d, wanted 2", app.stats.renders.Load())
+	}
+}
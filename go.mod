@@ -3,8 +3,11 @@ module go-breeders
 go 1.24.11
 
 require (
-	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/go-chi/chi/v5 v5.2.3 // indirect
-	github.com/go-sql-driver/mysql v1.9.3 // indirect
-	github.com/tsawler/toolbox v1.3.1 // indirect
+	github.com/go-chi/chi/v5 v5.2.3
+	github.com/go-sql-driver/mysql v1.9.3
+	github.com/tsawler/toolbox v1.3.1
+	golang.org/x/net v0.47.0
+	golang.org/x/sync v0.18.0
 )
+
+require filippo.io/edwards25519 v1.1.0 // indirect
@@ -12,6 +12,9 @@ type Models struct {
 }
 
 func New(conn *sql.DB) *Models {
+	if conn == nil {
+		panic("models: New needs a database connection")
+	}
 	repo = newMysqlRepository(conn)
 
 	return &Models{
@@ -19,6 +22,16 @@ func New(conn *sql.DB) *Models {
 	}
 }
 
+// NewTest returns models backed by the test repository, which needs no
+// database. It is meant for tests only.
+func NewTest() *Models {
+	repo = newTestRepository(nil)
+
+	return &Models{
+		DogBreed: DogBreed{},
+	}
+}
+
 type DogBreed struct {
 	ID               int    `json:"id"`
 	Breed            string `json:"breed"`